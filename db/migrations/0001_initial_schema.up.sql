@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS enderecos (
+	id SERIAL PRIMARY KEY,
+	logradouro VARCHAR(100) NOT NULL,
+	complemento VARCHAR(50),
+	numero INTEGER NOT NULL,
+	bairro VARCHAR(50) NOT NULL,
+	cidade VARCHAR(50) NOT NULL,
+	uf CHAR(2) NOT NULL CHECK (
+		uf IN (
+			'AC', 'AL', 'AP', 'AM', 'BA', 'CE', 'DF', 'ES', 'GO',
+			'MA', 'MT', 'MS', 'MG', 'PA', 'PB', 'PR', 'PE', 'PI',
+			'RJ', 'RN', 'RS', 'RO', 'RR', 'SC', 'SP', 'SE', 'TO'
+		)
+	),
+	cep VARCHAR(9) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS estabelecimentos (
+	id SERIAL PRIMARY KEY,
+	razao_social VARCHAR(255) NOT NULL,
+	cpf_cnpj VARCHAR(14) UNIQUE NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	endereco_id INTEGER NOT NULL,
+	FOREIGN KEY (endereco_id) REFERENCES enderecos(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	nome VARCHAR(50) NOT NULL,
+	sobrenome VARCHAR(50) NOT NULL,
+	password TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	role VARCHAR(20) NOT NULL CHECK (role IN ('OWNER', 'MANAGER', 'SELLER')),
+	estabelecimento_id INTEGER NOT NULL,
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS products (
+	id SERIAL PRIMARY KEY,
+	nome VARCHAR(150) NOT NULL,
+	sku VARCHAR(50) NOT NULL UNIQUE,
+	descricao TEXT NOT NULL,
+	valor NUMERIC(10,2) NOT NULL,
+	estoque NUMERIC(10,3) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	estabelecimento_id BIGINT NOT NULL,
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE
+);
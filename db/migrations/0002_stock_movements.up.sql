@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('ENTRADA', 'SAIDA', 'AJUSTE')),
+	quantidade NUMERIC(10,3) NOT NULL,
+	motivo TEXT NOT NULL,
+	estoque_anterior NUMERIC(10,3) NOT NULL,
+	estoque_posterior NUMERIC(10,3) NOT NULL,
+	user_id INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stock_transfers;
+DROP INDEX IF EXISTS products_estabelecimento_sku_idx;
+ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
@@ -0,0 +1,31 @@
+-- O SKU passa a ser único por estabelecimento para permitir transferências
+-- do mesmo item entre estabelecimentos.
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_key;
+CREATE UNIQUE INDEX IF NOT EXISTS products_estabelecimento_sku_idx ON products (estabelecimento_id, sku);
+
+CREATE TABLE IF NOT EXISTS stock_transfers (
+	id SERIAL PRIMARY KEY,
+	sku VARCHAR(50) NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade > 0),
+	status VARCHAR(20) NOT NULL CHECK (status IN ('SOLICITADA', 'EM_TRANSITO', 'RECEBIDA', 'CANCELADA')),
+	observacao TEXT NOT NULL DEFAULT '',
+	origem_estabelecimento_id INTEGER NOT NULL,
+	destino_estabelecimento_id INTEGER NOT NULL,
+	origem_product_id INTEGER NOT NULL,
+	destino_product_id INTEGER,
+	solicitado_por INTEGER,
+	enviado_por INTEGER,
+	recebido_por INTEGER,
+	enviado_em TIMESTAMP,
+	recebido_em TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	CHECK (origem_estabelecimento_id <> destino_estabelecimento_id),
+	FOREIGN KEY (origem_estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE,
+	FOREIGN KEY (destino_estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE,
+	FOREIGN KEY (origem_product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (destino_product_id) REFERENCES products(id) ON DELETE SET NULL,
+	FOREIGN KEY (solicitado_por) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (enviado_por) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (recebido_por) REFERENCES users(id) ON DELETE SET NULL
+);
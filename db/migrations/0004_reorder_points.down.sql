@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stock_alerts;
+ALTER TABLE products DROP COLUMN IF EXISTS ponto_de_pedido;
+ALTER TABLE products DROP COLUMN IF EXISTS estoque_minimo;
@@ -0,0 +1,16 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS estoque_minimo NUMERIC(10,3) NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS ponto_de_pedido NUMERIC(10,3) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS stock_alerts (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('PONTO_DE_PEDIDO', 'ESTOQUE_MINIMO')),
+	status VARCHAR(20) NOT NULL DEFAULT 'ABERTO' CHECK (status IN ('ABERTO', 'RESOLVIDO')),
+	estoque NUMERIC(10,3) NOT NULL,
+	limite NUMERIC(10,3) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	resolved_at TIMESTAMP,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS stock_alerts_aberto_idx ON stock_alerts (product_id, tipo) WHERE status = 'ABERTO';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_movement_lots;
+DROP TABLE IF EXISTS product_lots;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS product_lots (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	numero_lote VARCHAR(50) NOT NULL,
+	validade DATE,
+	quantidade_inicial NUMERIC(10,3) NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade >= 0),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (product_id, numero_lote),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS stock_movement_lots (
+	id SERIAL PRIMARY KEY,
+	movement_id INTEGER NOT NULL,
+	lot_id INTEGER NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL,
+	FOREIGN KEY (movement_id) REFERENCES stock_movements(id) ON DELETE CASCADE,
+	FOREIGN KEY (lot_id) REFERENCES product_lots(id) ON DELETE CASCADE
+);
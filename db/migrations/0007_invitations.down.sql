@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS acesso_global;
+DROP TABLE IF EXISTS invitations;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS invitations (
+	id SERIAL PRIMARY KEY,
+	email TEXT NOT NULL,
+	role VARCHAR(20) NOT NULL CHECK (role IN ('OWNER', 'MANAGER', 'SELLER')),
+	estabelecimento_id INTEGER NOT NULL,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	expires_at TIMESTAMP NOT NULL,
+	accepted_at TIMESTAMP,
+	created_by INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- OWNERs já existentes mantêm o acesso a todos os estabelecimentos. Quem entra
+-- pelo cadastro público ou por convite fica restrito ao próprio estabelecimento.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS acesso_global BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET acesso_global = TRUE WHERE role = 'OWNER';
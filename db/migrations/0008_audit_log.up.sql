@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER,
+	role VARCHAR(20) NOT NULL,
+	estabelecimento_id INTEGER,
+	entidade VARCHAR(30) NOT NULL,
+	entidade_id INTEGER NOT NULL,
+	acao VARCHAR(20) NOT NULL,
+	antes JSONB,
+	depois JSONB,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_entidade_idx ON audit_log (entidade, entidade_id);
+CREATE INDEX IF NOT EXISTS audit_log_estabelecimento_idx ON audit_log (estabelecimento_id, created_at);
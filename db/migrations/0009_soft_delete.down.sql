@@ -0,0 +1,7 @@
+DELETE FROM products WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+DELETE FROM estabelecimentos WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE estabelecimentos DROP COLUMN IF EXISTS deleted_at;
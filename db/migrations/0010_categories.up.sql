@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS categories (
+	id SERIAL PRIMARY KEY,
+	nome TEXT NOT NULL,
+	descricao TEXT NOT NULL DEFAULT '',
+	parent_id INTEGER,
+	estabelecimento_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (parent_id) REFERENCES categories(id),
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+CREATE UNIQUE INDEX IF NOT EXISTS categories_nome_idx ON categories (estabelecimento_id, COALESCE(parent_id, 0), LOWER(nome));
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS categoria_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS products_categoria_id_idx ON products (categoria_id);
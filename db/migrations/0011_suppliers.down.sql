@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS product_suppliers;
+DROP TABLE IF EXISTS suppliers;
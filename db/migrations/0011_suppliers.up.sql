@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+	id SERIAL PRIMARY KEY,
+	razao_social VARCHAR(255) NOT NULL,
+	nome_fantasia VARCHAR(255) NOT NULL DEFAULT '',
+	cpf_cnpj VARCHAR(14) NOT NULL,
+	contato VARCHAR(100) NOT NULL DEFAULT '',
+	email TEXT NOT NULL DEFAULT '',
+	telefone VARCHAR(20) NOT NULL DEFAULT '',
+	endereco_id INTEGER NOT NULL,
+	estabelecimento_id INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (estabelecimento_id, cpf_cnpj),
+	FOREIGN KEY (endereco_id) REFERENCES enderecos(id) ON DELETE CASCADE,
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_suppliers (
+	product_id INTEGER NOT NULL,
+	supplier_id INTEGER NOT NULL,
+	codigo_fornecedor VARCHAR(60) NOT NULL DEFAULT '',
+	ultimo_custo NUMERIC(10,2),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (product_id, supplier_id),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (supplier_id) REFERENCES suppliers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_suppliers_supplier_id_idx ON product_suppliers (supplier_id);
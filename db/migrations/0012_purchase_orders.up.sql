@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS purchase_orders (
+	id SERIAL PRIMARY KEY,
+	supplier_id INTEGER NOT NULL,
+	estabelecimento_id INTEGER NOT NULL,
+	status VARCHAR(30) NOT NULL DEFAULT 'RASCUNHO' CHECK (status IN ('RASCUNHO', 'ENVIADO', 'PARCIALMENTE_RECEBIDO', 'RECEBIDO', 'CANCELADO')),
+	observacao TEXT NOT NULL DEFAULT '',
+	valor_total NUMERIC(12,2) NOT NULL DEFAULT 0,
+	criado_por INTEGER,
+	aprovado_por INTEGER,
+	aprovado_em TIMESTAMP,
+	enviado_em TIMESTAMP,
+	recebido_em TIMESTAMP,
+	cancelado_em TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (supplier_id) REFERENCES suppliers(id),
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE,
+	FOREIGN KEY (criado_por) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (aprovado_por) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_items (
+	id SERIAL PRIMARY KEY,
+	purchase_order_id INTEGER NOT NULL,
+	product_id INTEGER NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade > 0),
+	quantidade_recebida NUMERIC(10,3) NOT NULL DEFAULT 0,
+	custo_unitario NUMERIC(10,2) NOT NULL CHECK (custo_unitario >= 0),
+	UNIQUE (purchase_order_id, product_id),
+	CHECK (quantidade_recebida <= quantidade),
+	FOREIGN KEY (purchase_order_id) REFERENCES purchase_orders(id) ON DELETE CASCADE,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS purchase_orders_estabelecimento_id_idx ON purchase_orders (estabelecimento_id, status);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sale_items;
+DROP TABLE IF EXISTS sales;
+ALTER TABLE estabelecimentos DROP COLUMN IF EXISTS permite_estoque_negativo;
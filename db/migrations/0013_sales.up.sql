@@ -0,0 +1,38 @@
+ALTER TABLE estabelecimentos ADD COLUMN IF NOT EXISTS permite_estoque_negativo BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS sales (
+	id SERIAL PRIMARY KEY,
+	estabelecimento_id INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'CONCLUIDA' CHECK (status IN ('CONCLUIDA', 'CANCELADA')),
+	forma_pagamento VARCHAR(20) NOT NULL CHECK (forma_pagamento IN ('DINHEIRO', 'CARTAO_CREDITO', 'CARTAO_DEBITO', 'PIX', 'BOLETO')),
+	subtotal NUMERIC(12,2) NOT NULL,
+	desconto NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (desconto >= 0),
+	total NUMERIC(12,2) NOT NULL CHECK (total >= 0),
+	observacao TEXT NOT NULL DEFAULT '',
+	vendedor_id INTEGER,
+	cancelado_por INTEGER,
+	cancelado_em TIMESTAMP,
+	motivo_cancelamento TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE,
+	FOREIGN KEY (vendedor_id) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (cancelado_por) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS sale_items (
+	id SERIAL PRIMARY KEY,
+	sale_id INTEGER NOT NULL,
+	product_id INTEGER NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade > 0),
+	valor_unitario NUMERIC(10,2) NOT NULL,
+	desconto NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (desconto >= 0),
+	total NUMERIC(12,2) NOT NULL,
+	movement_id INTEGER,
+	FOREIGN KEY (sale_id) REFERENCES sales(id) ON DELETE CASCADE,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (movement_id) REFERENCES stock_movements(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS sales_estabelecimento_id_idx ON sales (estabelecimento_id, created_at);
+CREATE INDEX IF NOT EXISTS sale_items_sale_id_idx ON sale_items (sale_id);
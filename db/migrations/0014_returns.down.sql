@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS quarantine_movements;
+DROP TABLE IF EXISTS return_items;
+DROP TABLE IF EXISTS returns;
+ALTER TABLE products DROP COLUMN IF EXISTS estoque_avariado;
@@ -0,0 +1,43 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS estoque_avariado NUMERIC(10,3) NOT NULL DEFAULT 0 CHECK (estoque_avariado >= 0);
+
+CREATE TABLE IF NOT EXISTS returns (
+	id SERIAL PRIMARY KEY,
+	estabelecimento_id INTEGER NOT NULL,
+	sale_id INTEGER,
+	motivo TEXT NOT NULL,
+	documento_cliente VARCHAR(14) NOT NULL DEFAULT '',
+	user_id INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE,
+	FOREIGN KEY (sale_id) REFERENCES sales(id) ON DELETE SET NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS return_items (
+	id SERIAL PRIMARY KEY,
+	return_id INTEGER NOT NULL,
+	product_id INTEGER NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade > 0),
+	destino VARCHAR(20) NOT NULL CHECK (destino IN ('ESTOQUE', 'AVARIADO')),
+	FOREIGN KEY (return_id) REFERENCES returns(id) ON DELETE CASCADE,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS quarantine_movements (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('ENTRADA', 'BAIXA', 'REESTOQUE')),
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade > 0),
+	motivo TEXT NOT NULL,
+	estoque_anterior NUMERIC(10,3) NOT NULL,
+	estoque_posterior NUMERIC(10,3) NOT NULL,
+	return_id INTEGER,
+	user_id INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (return_id) REFERENCES returns(id) ON DELETE SET NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS return_items_return_id_idx ON return_items (return_id);
+CREATE INDEX IF NOT EXISTS quarantine_movements_product_id_idx ON quarantine_movements (product_id, created_at);
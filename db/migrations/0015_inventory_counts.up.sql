@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS inventory_counts (
+	id SERIAL PRIMARY KEY,
+	estabelecimento_id INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'ABERTA' CHECK (status IN ('ABERTA', 'FECHADA', 'CANCELADA')),
+	observacao TEXT NOT NULL DEFAULT '',
+	aberto_por INTEGER,
+	fechado_por INTEGER,
+	fechado_em TIMESTAMP,
+	cancelado_em TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE,
+	FOREIGN KEY (aberto_por) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (fechado_por) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Apenas uma contagem aberta por estabelecimento.
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_counts_open_idx ON inventory_counts (estabelecimento_id) WHERE status = 'ABERTA';
+
+CREATE TABLE IF NOT EXISTS inventory_count_items (
+	id SERIAL PRIMARY KEY,
+	inventory_count_id INTEGER NOT NULL,
+	product_id INTEGER NOT NULL,
+	estoque_esperado NUMERIC(10,3) NOT NULL,
+	quantidade_contada NUMERIC(10,3) CHECK (quantidade_contada >= 0),
+	contado_por INTEGER,
+	contado_em TIMESTAMP,
+	movement_id INTEGER,
+	UNIQUE (inventory_count_id, product_id),
+	FOREIGN KEY (inventory_count_id) REFERENCES inventory_counts(id) ON DELETE CASCADE,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (contado_por) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (movement_id) REFERENCES stock_movements(id) ON DELETE SET NULL
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS product_attributes;
+DROP INDEX IF EXISTS products_variant_atributos_idx;
+DROP INDEX IF EXISTS products_parent_id_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS atributos;
+ALTER TABLE products DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,19 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES products(id) ON DELETE CASCADE;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS atributos JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS products_parent_id_idx ON products (parent_id);
+
+-- Duas variantes ativas do mesmo produto não podem ter a mesma combinação.
+CREATE UNIQUE INDEX IF NOT EXISTS products_variant_atributos_idx ON products (parent_id, atributos)
+	WHERE parent_id IS NOT NULL AND deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS product_attributes (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	nome VARCHAR(50) NOT NULL,
+	valores TEXT[] NOT NULL DEFAULT '{}',
+	posicao INTEGER NOT NULL DEFAULT 0,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS product_attributes_nome_idx ON product_attributes (product_id, LOWER(nome));
@@ -0,0 +1,3 @@
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS kit_movement_id;
+DROP TABLE IF EXISTS product_kit_components;
+ALTER TABLE products DROP COLUMN IF EXISTS tipo;
@@ -0,0 +1,16 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tipo VARCHAR(20) NOT NULL DEFAULT 'SIMPLES' CHECK (tipo IN ('SIMPLES', 'KIT'));
+
+CREATE TABLE IF NOT EXISTS product_kit_components (
+	kit_id INTEGER NOT NULL,
+	component_id INTEGER NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade > 0),
+	PRIMARY KEY (kit_id, component_id),
+	CHECK (kit_id <> component_id),
+	FOREIGN KEY (kit_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (component_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_kit_components_component_id_idx ON product_kit_components (component_id);
+
+-- Movimentações de componentes geradas pela movimentação de um kit.
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS kit_movement_id INTEGER REFERENCES stock_movements(id) ON DELETE SET NULL;
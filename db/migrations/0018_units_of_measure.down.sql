@@ -0,0 +1,9 @@
+ALTER TABLE sale_items DROP COLUMN IF EXISTS fator;
+ALTER TABLE sale_items DROP COLUMN IF EXISTS unidade;
+ALTER TABLE purchase_order_items DROP COLUMN IF EXISTS fator;
+ALTER TABLE purchase_order_items DROP COLUMN IF EXISTS unidade;
+ALTER TABLE products DROP COLUMN IF EXISTS fator_venda;
+ALTER TABLE products DROP COLUMN IF EXISTS unidade_venda;
+ALTER TABLE products DROP COLUMN IF EXISTS fator_compra;
+ALTER TABLE products DROP COLUMN IF EXISTS unidade_compra;
+ALTER TABLE products DROP COLUMN IF EXISTS unidade;
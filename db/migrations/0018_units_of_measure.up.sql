@@ -0,0 +1,13 @@
+-- Unidade de estoque do produto e unidades de compra e venda, com o fator que
+-- converte cada uma para a unidade de estoque.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unidade VARCHAR(5) NOT NULL DEFAULT 'UN' CHECK (unidade IN ('UN', 'KG', 'L', 'CX'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unidade_compra VARCHAR(5) NOT NULL DEFAULT 'UN' CHECK (unidade_compra IN ('UN', 'KG', 'L', 'CX'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS fator_compra NUMERIC(10,3) NOT NULL DEFAULT 1 CHECK (fator_compra > 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unidade_venda VARCHAR(5) NOT NULL DEFAULT 'UN' CHECK (unidade_venda IN ('UN', 'KG', 'L', 'CX'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS fator_venda NUMERIC(10,3) NOT NULL DEFAULT 1 CHECK (fator_venda > 0);
+
+-- Itens guardam a unidade e o fator vigentes quando foram registrados.
+ALTER TABLE purchase_order_items ADD COLUMN IF NOT EXISTS unidade VARCHAR(5) NOT NULL DEFAULT 'UN';
+ALTER TABLE purchase_order_items ADD COLUMN IF NOT EXISTS fator NUMERIC(10,3) NOT NULL DEFAULT 1;
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS unidade VARCHAR(5) NOT NULL DEFAULT 'UN';
+ALTER TABLE sale_items ADD COLUMN IF NOT EXISTS fator NUMERIC(10,3) NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS products_codigo_balanca_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS modo_balanca;
+ALTER TABLE products DROP COLUMN IF EXISTS codigo_balanca;
+DROP TABLE IF EXISTS product_barcodes;
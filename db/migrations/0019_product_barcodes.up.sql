@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS product_barcodes (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	estabelecimento_id INTEGER NOT NULL,
+	codigo VARCHAR(14) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (estabelecimento_id, codigo),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_barcodes_product_id_idx ON product_barcodes (product_id);
+
+-- Código do produto nas etiquetas de balança (prefixo 2), que trazem o peso ou
+-- o preço dentro do código de barras.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS codigo_balanca VARCHAR(5);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS modo_balanca VARCHAR(10) CHECK (modo_balanca IN ('PESO', 'PRECO'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS products_codigo_balanca_idx ON products (estabelecimento_id, codigo_balanca);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS location_moves;
+DROP TABLE IF EXISTS stock_movement_locations;
+DROP TABLE IF EXISTS product_locations;
+DROP TABLE IF EXISTS storage_locations;
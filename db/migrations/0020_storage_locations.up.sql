@@ -0,0 +1,73 @@
+-- Locais de armazenagem em hierarquia área > corredor > prateleira > posição.
+-- Cada estabelecimento tem um local padrão, que recebe o que entra sem local.
+CREATE TABLE IF NOT EXISTS storage_locations (
+	id SERIAL PRIMARY KEY,
+	estabelecimento_id INTEGER NOT NULL,
+	parent_id INTEGER,
+	tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('AREA', 'CORREDOR', 'PRATELEIRA', 'POSICAO')),
+	codigo VARCHAR(30) NOT NULL,
+	descricao VARCHAR(255) NOT NULL DEFAULT '',
+	padrao BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (estabelecimento_id, codigo),
+	CHECK (parent_id <> id),
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE,
+	FOREIGN KEY (parent_id) REFERENCES storage_locations(id) ON DELETE RESTRICT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS storage_locations_padrao_idx ON storage_locations (estabelecimento_id) WHERE padrao;
+CREATE INDEX IF NOT EXISTS storage_locations_parent_id_idx ON storage_locations (parent_id);
+
+-- Saldo de cada produto por local. products.estoque é a soma dos locais.
+CREATE TABLE IF NOT EXISTS product_locations (
+	product_id INTEGER NOT NULL,
+	location_id INTEGER NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL DEFAULT 0,
+	PRIMARY KEY (product_id, location_id),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (location_id) REFERENCES storage_locations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_locations_location_id_idx ON product_locations (location_id);
+
+-- Quanto cada movimentação de estoque alterou em cada local, com sinal.
+CREATE TABLE IF NOT EXISTS stock_movement_locations (
+	id SERIAL PRIMARY KEY,
+	movement_id INTEGER NOT NULL,
+	location_id INTEGER,
+	quantidade NUMERIC(10,3) NOT NULL,
+	FOREIGN KEY (movement_id) REFERENCES stock_movements(id) ON DELETE CASCADE,
+	FOREIGN KEY (location_id) REFERENCES storage_locations(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS stock_movement_locations_movement_id_idx ON stock_movement_locations (movement_id);
+
+-- Endereçamentos e trocas de local, que não alteram o estoque total.
+CREATE TABLE IF NOT EXISTS location_moves (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	origem_location_id INTEGER,
+	destino_location_id INTEGER,
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade > 0),
+	motivo TEXT NOT NULL DEFAULT '',
+	user_id INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (origem_location_id) REFERENCES storage_locations(id) ON DELETE SET NULL,
+	FOREIGN KEY (destino_location_id) REFERENCES storage_locations(id) ON DELETE SET NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS location_moves_product_id_idx ON location_moves (product_id);
+
+INSERT INTO storage_locations (estabelecimento_id, tipo, codigo, descricao, padrao)
+SELECT id, 'AREA', 'PADRAO', 'Local padrão', TRUE FROM estabelecimentos
+ON CONFLICT DO NOTHING;
+
+INSERT INTO product_locations (product_id, location_id, quantidade)
+SELECT p.id, l.id, p.estoque
+FROM products p
+JOIN storage_locations l ON l.estabelecimento_id = p.estabelecimento_id AND l.padrao
+WHERE p.estoque <> 0
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,20 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS estoque_reservado NUMERIC(10,3) NOT NULL DEFAULT 0 CHECK (estoque_reservado >= 0);
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	quantidade NUMERIC(10,3) NOT NULL CHECK (quantidade > 0),
+	referencia VARCHAR(100) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'ATIVA' CHECK (status IN ('ATIVA', 'CONFIRMADA', 'LIBERADA', 'EXPIRADA')),
+	expira_em TIMESTAMP NOT NULL,
+	movement_id INTEGER,
+	user_id INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	finalizada_em TIMESTAMP,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (movement_id) REFERENCES stock_movements(id) ON DELETE SET NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS stock_reservations_product_id_idx ON stock_reservations (product_id, created_at);
+CREATE INDEX IF NOT EXISTS stock_reservations_expira_em_idx ON stock_reservations (expira_em) WHERE status = 'ATIVA';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS stock_movements_product_ledger_idx;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS custo_unitario;
+ALTER TABLE estabelecimentos DROP COLUMN IF EXISTS metodo_custeio;
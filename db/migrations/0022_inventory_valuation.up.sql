@@ -0,0 +1,13 @@
+ALTER TABLE estabelecimentos ADD COLUMN IF NOT EXISTS metodo_custeio VARCHAR(10) NOT NULL DEFAULT 'MEDIO' CHECK (metodo_custeio IN ('MEDIO', 'PEPS'));
+
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS custo_unitario NUMERIC(12,4) CHECK (custo_unitario >= 0);
+
+-- Recebimentos de pedidos de compra já registrados passam a ter o custo do
+-- item, convertido para a unidade de estoque.
+UPDATE stock_movements m
+SET custo_unitario = ROUND(i.custo_unitario / i.fator, 4)
+FROM purchase_order_items i
+WHERE m.tipo = 'ENTRADA' AND m.custo_unitario IS NULL AND i.product_id = m.product_id AND i.fator > 0
+AND m.motivo = 'Recebimento do pedido de compra #' || i.purchase_order_id;
+
+CREATE INDEX IF NOT EXISTS stock_movements_product_ledger_idx ON stock_movements (product_id, created_at, id);
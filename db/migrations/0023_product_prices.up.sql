@@ -0,0 +1,27 @@
+-- Histórico de preços de venda. Cada linha aplicada vale de vigente_de até
+-- vigente_ate (aberto no preço atual); linhas sem aplicado_em são mudanças
+-- agendadas que o agendador aplica quando vigente_de chega.
+CREATE TABLE IF NOT EXISTS product_prices (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL,
+	valor NUMERIC(10,2) NOT NULL CHECK (valor >= 0),
+	vigente_de TIMESTAMP NOT NULL,
+	vigente_ate TIMESTAMP,
+	aplicado_em TIMESTAMP,
+	motivo TEXT NOT NULL DEFAULT '',
+	user_id INTEGER,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	CHECK (vigente_ate IS NULL OR vigente_ate >= vigente_de),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS product_prices_product_id_idx ON product_prices (product_id, vigente_de);
+CREATE UNIQUE INDEX IF NOT EXISTS product_prices_current_idx ON product_prices (product_id) WHERE aplicado_em IS NOT NULL AND vigente_ate IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS product_prices_scheduled_idx ON product_prices (product_id, vigente_de) WHERE aplicado_em IS NULL;
+CREATE INDEX IF NOT EXISTS product_prices_pending_idx ON product_prices (vigente_de) WHERE aplicado_em IS NULL;
+
+INSERT INTO product_prices (product_id, valor, vigente_de, aplicado_em, motivo)
+SELECT p.id, p.valor, p.created_at, p.created_at, 'Preço inicial'
+FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM product_prices pp WHERE pp.product_id = p.id);
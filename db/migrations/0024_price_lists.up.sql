@@ -0,0 +1,32 @@
+-- Listas de preço por estabelecimento. O ajuste percentual da lista vale para
+-- os produtos sem preço próprio nela; cada item pode fixar um valor ou um
+-- percentual sobre o valor base a partir de uma quantidade mínima.
+CREATE TABLE IF NOT EXISTS price_lists (
+	id SERIAL PRIMARY KEY,
+	estabelecimento_id INTEGER NOT NULL,
+	nome VARCHAR(60) NOT NULL,
+	descricao TEXT NOT NULL DEFAULT '',
+	ajuste_percentual NUMERIC(7,2) NOT NULL DEFAULT 0 CHECK (ajuste_percentual >= -100),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (estabelecimento_id) REFERENCES estabelecimentos(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS price_lists_nome_idx ON price_lists (estabelecimento_id, LOWER(nome));
+
+CREATE TABLE IF NOT EXISTS price_list_items (
+	id SERIAL PRIMARY KEY,
+	price_list_id INTEGER NOT NULL,
+	product_id INTEGER NOT NULL,
+	quantidade_minima NUMERIC(10,3) NOT NULL DEFAULT 1 CHECK (quantidade_minima > 0),
+	valor NUMERIC(10,2) CHECK (valor >= 0),
+	ajuste_percentual NUMERIC(7,2) CHECK (ajuste_percentual >= -100),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (price_list_id, product_id, quantidade_minima),
+	CHECK ((valor IS NULL) <> (ajuste_percentual IS NULL)),
+	FOREIGN KEY (price_list_id) REFERENCES price_lists(id) ON DELETE CASCADE,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS price_list_items_product_id_idx ON price_list_items (product_id);
@@ -0,0 +1 @@
+UPDATE product_barcodes SET codigo = RIGHT(codigo, 13) WHERE codigo LIKE '0%';
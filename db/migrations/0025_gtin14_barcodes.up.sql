@@ -0,0 +1,22 @@
+-- Os códigos passam a ser gravados em GTIN-14. Se o mesmo item já estiver
+-- cadastrado em mais de um formato no estabelecimento, a migração é
+-- interrompida com a lista dos conflitos, para que sejam resolvidos à mão.
+DO $$
+DECLARE
+	conflitos TEXT;
+BEGIN
+	SELECT string_agg(format('estabelecimento %s: %s', estabelecimento_id, codigos), '; ')
+	INTO conflitos
+	FROM (
+		SELECT estabelecimento_id, string_agg(codigo, ', ' ORDER BY id) AS codigos
+		FROM product_barcodes
+		GROUP BY estabelecimento_id, LPAD(codigo, 14, '0')
+		HAVING COUNT(*) > 1
+	) duplicados;
+
+	IF conflitos IS NOT NULL THEN
+		RAISE EXCEPTION 'códigos de barras duplicados em GTIN-14: %', conflitos;
+	END IF;
+END $$;
+
+UPDATE product_barcodes SET codigo = LPAD(codigo, 14, '0') WHERE LENGTH(codigo) < 14;
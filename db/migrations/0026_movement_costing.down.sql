@@ -0,0 +1 @@
+DROP INDEX IF EXISTS stock_movements_estabelecimento_id_idx;
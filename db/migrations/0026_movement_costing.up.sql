@@ -0,0 +1,17 @@
+-- Cada movimentação guarda o estabelecimento e o método de custeio vigentes
+-- quando foi registrada, para que a avaliação em uma data passada não dependa
+-- de onde o produto está hoje nem do método atual do estabelecimento. As
+-- movimentações anteriores recebem os valores atuais do produto.
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS estabelecimento_id INTEGER REFERENCES estabelecimentos(id) ON DELETE CASCADE;
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS metodo_custeio VARCHAR(10) CHECK (metodo_custeio IN ('MEDIO', 'PEPS'));
+
+UPDATE stock_movements m
+SET estabelecimento_id = p.estabelecimento_id, metodo_custeio = e.metodo_custeio
+FROM products p
+JOIN estabelecimentos e ON e.id = p.estabelecimento_id
+WHERE p.id = m.product_id AND m.estabelecimento_id IS NULL;
+
+ALTER TABLE stock_movements ALTER COLUMN estabelecimento_id SET NOT NULL;
+ALTER TABLE stock_movements ALTER COLUMN metodo_custeio SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS stock_movements_estabelecimento_id_idx ON stock_movements (estabelecimento_id);
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
 	"github.com/AntonioGuilhermeDev/InventoryHubApis/routes"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -18,6 +21,22 @@ func main() {
 	}
 
 	db.InitDB()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	err = db.MigrateUp()
+	if err != nil {
+		panic(fmt.Sprintf("Erro ao aplicar migrações: %v", err))
+	}
+
+	go models.StartStockAlertChecker(time.Minute)
+	go models.StartReservationSweeper(30 * time.Second)
+	go models.StartPriceScheduler(time.Minute)
+	go models.StartRevokedTokenSweeper(time.Hour)
+
 	server := gin.Default()
 
 	server.Use(cors.New(cors.Config{
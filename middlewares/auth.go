@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
 	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -56,8 +57,34 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 		userId := int64(userIdFloat)
+
+		jti, ok := claims["jti"].(string)
+		if !ok || jti == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Token inválido: jti ausente"})
+			return
+		}
+
+		revoked, err := models.IsTokenRevoked(jti)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Erro interno"})
+			return
+		}
+
+		if revoked {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Token revogado"})
+			return
+		}
+
+		expiresAt, err := claims.GetExpirationTime()
+		if err != nil || expiresAt == nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Token inválido"})
+			return
+		}
+
 		ctx.Set("userId", userId)
 		ctx.Set("role", claims["role"])
+		ctx.Set("jti", jti)
+		ctx.Set("tokenExpiresAt", expiresAt.Time)
 
 	}
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+// runMigrate trata o subcomando "migrate up|down|status".
+func runMigrate(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Uso: migrate up|down|status")
+		os.Exit(1)
+	}
+
+	var err error
+
+	switch args[0] {
+	case "up":
+		err = db.MigrateUp()
+	case "down":
+		err = db.MigrateDown()
+	case "status":
+		var status []db.MigrationStatus
+		status, err = db.GetMigrationStatus()
+		for _, migration := range status {
+			if migration.AppliedAt != nil {
+				fmt.Printf("%04d_%s\taplicada em %s\n", migration.Version, migration.Name, migration.AppliedAt.Format("02/01/2006 15:04:05"))
+			} else {
+				fmt.Printf("%04d_%s\tpendente\n", migration.Version, migration.Name)
+			}
+		}
+	default:
+		fmt.Println("Uso: migrate up|down|status")
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Println("Erro ao executar migrações:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	AuditUser          = "USER"
+	AuditProduct       = "PRODUCT"
+	AuditEstablishment = "ESTABLISHMENT"
+	AuditCategory      = "CATEGORY"
+	AuditSupplier      = "SUPPLIER"
+
+	AuditCreate  = "CREATE"
+	AuditUpdate  = "UPDATE"
+	AuditDelete  = "DELETE"
+	AuditRestore = "RESTORE"
+	AuditPurge   = "PURGE"
+)
+
+// Actor identifica quem executou uma alteração, a partir das claims do JWT.
+type Actor struct {
+	UserID int64
+	Role   string
+}
+
+// SystemActor identifica as alterações feitas por rotinas agendadas, que não
+// têm usuário.
+var SystemActor = Actor{Role: "SYSTEM"}
+
+type AuditEntry struct {
+	ID                int64           `json:"id"`
+	UserID            *int64          `json:"user_id"`
+	Role              string          `json:"role"`
+	EstabelecimentoID *int64          `json:"estabelecimento_id"`
+	Entidade          string          `json:"entidade"`
+	EntidadeID        int64           `json:"entidade_id"`
+	Acao              string          `json:"acao"`
+	Antes             json.RawMessage `json:"antes"`
+	Depois            json.RawMessage `json:"depois"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+type AuditFilter struct {
+	Entidade   string
+	EntidadeID string
+	UserID     string
+	StartDate  string
+	EndDate    string
+}
+
+var AuditSortColumns = map[string]string{
+	"id":         "id",
+	"created_at": "created_at",
+	"entidade":   "entidade",
+	"acao":       "acao",
+}
+
+// auditIgnoredFields não entram no diff por mudarem a cada gravação.
+var auditIgnoredFields = map[string]bool{"updated_at": true}
+
+func toAuditMap(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	err = json.Unmarshal(raw, &fields)
+
+	return fields, err
+}
+
+// auditDiff reduz o antes/depois aos campos que mudaram. Em criações e
+// exclusões um dos lados é nulo e o outro é registrado por completo.
+func auditDiff(before, after interface{}) ([]byte, []byte, error) {
+	beforeMap, err := toAuditMap(before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	afterMap, err := toAuditMap(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if beforeMap != nil && afterMap != nil {
+		for key := range auditIgnoredFields {
+			delete(beforeMap, key)
+			delete(afterMap, key)
+		}
+
+		for key := range afterMap {
+			if reflect.DeepEqual(beforeMap[key], afterMap[key]) {
+				delete(beforeMap, key)
+				delete(afterMap, key)
+			}
+		}
+	}
+
+	var beforeJSON, afterJSON []byte
+
+	if beforeMap != nil {
+		beforeJSON, err = json.Marshal(beforeMap)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if afterMap != nil {
+		afterJSON, err = json.Marshal(afterMap)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return beforeJSON, afterJSON, nil
+}
+
+// RecordAudit grava a entrada de auditoria na mesma transação da alteração,
+// de modo que as duas são confirmadas ou desfeitas juntas.
+func RecordAudit(tx *sql.Tx, actor Actor, entidade string, entidadeId, estabelecimentoId int64, acao string, before, after interface{}) error {
+	beforeJSON, afterJSON, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	var userId, estabId interface{}
+	if actor.UserID != 0 {
+		userId = actor.UserID
+	}
+	if estabelecimentoId != 0 {
+		estabId = estabelecimentoId
+	}
+
+	var antes, depois interface{}
+	if beforeJSON != nil {
+		antes = string(beforeJSON)
+	}
+	if afterJSON != nil {
+		depois = string(afterJSON)
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO audit_log(user_id, role, estabelecimento_id, entidade, entidade_id, acao, antes, depois)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8)`,
+		userId, actor.Role, estabId, entidade, entidadeId, acao, antes, depois,
+	)
+
+	return err
+}
+
+func GetAuditLog(role, userId string, filter AuditFilter, params ListParams) ([]AuditEntry, ListResult, error) {
+	baseQuery := `SELECT id, user_id, role, estabelecimento_id, entidade, entidade_id, acao, antes, depois, created_at
+	FROM audit_log WHERE 1=1`
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	if filter.Entidade != "" {
+		args = append(args, filter.Entidade)
+		baseQuery += fmt.Sprintf(" AND entidade = $%d", len(args))
+	}
+	if entidadeId, err := strconv.ParseInt(filter.EntidadeID, 10, 64); err == nil {
+		args = append(args, entidadeId)
+		baseQuery += fmt.Sprintf(" AND entidade_id = $%d", len(args))
+	}
+	if filterUserId, err := strconv.ParseInt(filter.UserID, 10, 64); err == nil {
+		args = append(args, filterUserId)
+		baseQuery += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+	if filter.StartDate != "" && filter.EndDate != "" {
+		layoutBR := "02/01/2006"
+		startDate, err1 := time.Parse(layoutBR, filter.StartDate)
+		endDate, err2 := time.Parse(layoutBR, filter.EndDate)
+		if err1 == nil && err2 == nil {
+			endDate = endDate.Add(time.Hour*23 + time.Minute*59 + time.Second*59)
+
+			args = append(args, startDate, endDate)
+			baseQuery += fmt.Sprintf(" AND created_at BETWEEN $%d AND $%d", len(args)-1, len(args))
+		}
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var entries []AuditEntry
+
+	for rows.Next() {
+		var entry AuditEntry
+		var antes, depois []byte
+		err := rows.Scan(&entry.ID, &entry.UserID, &entry.Role, &entry.EstabelecimentoID, &entry.Entidade, &entry.EntidadeID, &entry.Acao, &antes, &depois, &entry.CreatedAt)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		entry.Antes = antes
+		entry.Depois = depois
+		entries = append(entries, entry)
+	}
+
+	fetched := len(entries)
+	if fetched > params.PerPage {
+		entries = entries[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(entries) > 0 {
+		lastID = entries[len(entries)-1].ID
+	}
+
+	return entries, params.result(total, fetched, lastID), nil
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token inválido ou expirado")
+	ErrRefreshTokenReuse   = errors.New("refresh token reutilizado, sessão revogada")
+)
+
+// RefreshGrant é o resultado de uma rotação de refresh token com os dados
+// necessários para emitir um novo access token.
+type RefreshGrant struct {
+	UserID       int64
+	Email        string
+	Role         string
+	RefreshToken string
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func issueRefreshToken(q queryRower, userId int64, familyId string) (string, error) {
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	var id int64
+	err = q.QueryRow(
+		"INSERT INTO refresh_tokens(user_id, token_hash, family_id, expires_at) VALUES($1, $2, $3, $4) RETURNING id",
+		userId, utils.HashToken(token), familyId, time.Now().Add(utils.RefreshTokenTTL),
+	).Scan(&id)
+
+	return token, err
+}
+
+// IssueRefreshToken cria um refresh token em uma nova família, usada para
+// detectar reuso após as rotações.
+func IssueRefreshToken(userId int64) (string, error) {
+	familyId, err := utils.GenerateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	return issueRefreshToken(db.DB, userId, familyId)
+}
+
+// RotateRefreshToken troca um refresh token válido por um novo da mesma
+// família. Apresentar um token já rotacionado revoga a família inteira.
+func RotateRefreshToken(token string) (*RefreshGrant, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var familyId string
+	var expiresAt time.Time
+	var usedAt, revokedAt *time.Time
+	grant := RefreshGrant{}
+
+	err = tx.QueryRow(`SELECT rt.id, rt.user_id, rt.family_id, rt.expires_at, rt.used_at, rt.revoked_at, u.email, u.role
+	FROM refresh_tokens rt
+	JOIN users u ON u.id = rt.user_id
+	WHERE rt.token_hash = $1 AND u.deleted_at IS NULL
+	FOR UPDATE OF rt`, utils.HashToken(token)).Scan(&id, &grant.UserID, &familyId, &expiresAt, &usedAt, &revokedAt, &grant.Email, &grant.Role)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if usedAt != nil {
+		_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", familyId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ErrRefreshTokenReuse
+	}
+
+	if time.Now().After(expiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	_, err = tx.Exec("UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	grant.RefreshToken, err = issueRefreshToken(tx, grant.UserID, familyId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return &grant, nil
+}
+
+// RevokeRefreshToken revoga a família do refresh token informado, desde que
+// ele pertença ao usuário.
+func RevokeRefreshToken(token string, userId int64) error {
+	_, err := db.DB.Exec(`UPDATE refresh_tokens SET revoked_at = NOW()
+	WHERE revoked_at IS NULL AND family_id = (
+		SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2
+	)`, utils.HashToken(token), userId)
+
+	return err
+}
+
+func RevokeAccessToken(jti string, expiresAt time.Time) error {
+	_, err := db.DB.Exec(
+		"INSERT INTO revoked_tokens(jti, expires_at) VALUES($1, $2) ON CONFLICT (jti) DO NOTHING",
+		jti, expiresAt,
+	)
+
+	return err
+}
+
+func IsTokenRevoked(jti string) (bool, error) {
+	var exists bool
+	err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&exists)
+	return exists, err
+}
+
+// PurgeRevokedTokens apaga as revogações de access tokens que já expiraram,
+// pois um token expirado é recusado de qualquer forma.
+func PurgeRevokedTokens() error {
+	_, err := db.DB.Exec("DELETE FROM revoked_tokens WHERE expires_at < NOW()")
+	return err
+}
+
+// StartRevokedTokenSweeper executa PurgeRevokedTokens periodicamente. Deve ser
+// chamado em uma goroutine própria.
+func StartRevokedTokenSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := PurgeRevokedTokens()
+		if err != nil {
+			log.Println("Erro ao remover tokens revogados expirados:", err)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
+)
+
+const (
+	ScaleModePeso  = "PESO"
+	ScaleModePreco = "PRECO"
+)
+
+var (
+	ErrInvalidBarcode   = errors.New("código de barras GTIN inválido")
+	ErrDuplicateBarcode = errors.New("código de barras já cadastrado no estabelecimento")
+	ErrInvalidScaleCode = errors.New("código de balança inválido para o produto")
+)
+
+var scaleCodePattern = regexp.MustCompile(`^\d{5}$`)
+
+type ProductBarcode struct {
+	ID        int64     `json:"id"`
+	ProductID int64     `json:"product_id"`
+	Codigo    string    `json:"codigo" binding:"required"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BarcodeLookup é o resultado da leitura de um código de barras. Etiquetas de
+// balança trazem também a quantidade, na unidade de venda, e o valor total.
+type BarcodeLookup struct {
+	Codigo     string   `json:"codigo"`
+	Balanca    bool     `json:"balanca"`
+	Quantidade *float64 `json:"quantidade,omitempty"`
+	ValorTotal *float64 `json:"valor_total,omitempty"`
+	Produto    *Product `json:"produto"`
+}
+
+func GetProductBarcodes(productId int64) ([]ProductBarcode, error) {
+	rows, err := db.DB.Query("SELECT id, product_id, codigo, created_at FROM product_barcodes WHERE product_id = $1 ORDER BY id", productId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	barcodes := []ProductBarcode{}
+
+	for rows.Next() {
+		var barcode ProductBarcode
+		err := rows.Scan(&barcode.ID, &barcode.ProductID, &barcode.Codigo, &barcode.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		barcodes = append(barcodes, barcode)
+	}
+
+	return barcodes, nil
+}
+
+// Save vincula o código ao produto. O código precisa ser um GTIN válido e não
+// pode estar em outro produto do mesmo estabelecimento. Ele é gravado como
+// GTIN-14, o que torna iguais as leituras GTIN-12 e GTIN-13 do mesmo item.
+func (b *ProductBarcode) Save(tx *sql.Tx) error {
+	codigo, err := utils.ValidateGTIN(b.Codigo)
+	if err != nil {
+		return ErrInvalidBarcode
+	}
+	b.Codigo = codigo
+
+	var estabelecimentoId int64
+	err = tx.QueryRow("SELECT estabelecimento_id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", b.ProductID).Scan(&estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM product_barcodes WHERE estabelecimento_id = $1 AND codigo = $2)",
+		estabelecimentoId, b.Codigo,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrDuplicateBarcode
+	}
+
+	return tx.QueryRow(
+		"INSERT INTO product_barcodes(product_id, estabelecimento_id, codigo) VALUES($1, $2, $3) RETURNING id, created_at",
+		b.ProductID, estabelecimentoId, b.Codigo,
+	).Scan(&b.ID, &b.CreatedAt)
+}
+
+func DeleteProductBarcode(tx *sql.Tx, productId int64, codigo string) error {
+	result, err := tx.Exec("DELETE FROM product_barcodes WHERE product_id = $1 AND codigo = $2", productId, utils.NormalizeGTIN(codigo))
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// moveBarcodes acompanha a troca de estabelecimento do produto, mantendo os
+// códigos únicos no destino.
+func moveBarcodes(tx *sql.Tx, productId, estabelecimentoId int64) error {
+	var conflict bool
+	err := tx.QueryRow(`SELECT EXISTS(
+		SELECT 1 FROM product_barcodes b
+		JOIN product_barcodes own ON own.codigo = b.codigo AND own.product_id = $1
+		WHERE b.estabelecimento_id = $2 AND b.product_id <> $1
+	)`, productId, estabelecimentoId).Scan(&conflict)
+	if err != nil {
+		return err
+	}
+
+	if conflict {
+		return ErrDuplicateBarcode
+	}
+
+	_, err = tx.Exec("UPDATE product_barcodes SET estabelecimento_id = $1 WHERE product_id = $2", estabelecimentoId, productId)
+	return err
+}
+
+// normalizeScaleCode valida o código de balança do produto. Etiquetas de
+// balança só fazem sentido para unidades de venda fracionáveis, e as de peso
+// para produtos vendidos em KG.
+func (p *Product) normalizeScaleCode(tx *sql.Tx) error {
+	if p.CodigoBalanca != nil {
+		codigo := strings.TrimSpace(*p.CodigoBalanca)
+		p.CodigoBalanca = &codigo
+	}
+
+	if p.CodigoBalanca == nil || *p.CodigoBalanca == "" {
+		p.CodigoBalanca = nil
+		p.ModoBalanca = nil
+		return nil
+	}
+
+	if !scaleCodePattern.MatchString(*p.CodigoBalanca) || p.ModoBalanca == nil {
+		return ErrInvalidScaleCode
+	}
+
+	venda, ok := findUnit(p.UnidadeVenda)
+	if !ok || !venda.Fracionavel {
+		return ErrInvalidScaleCode
+	}
+
+	switch *p.ModoBalanca {
+	case ScaleModePeso:
+		if p.UnidadeVenda != UnitQuilograma {
+			return ErrInvalidScaleCode
+		}
+	case ScaleModePreco:
+	default:
+		return ErrInvalidScaleCode
+	}
+
+	var exists bool
+	err := tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM products WHERE estabelecimento_id = $1 AND codigo_balanca = $2 AND id <> $3)",
+		p.EstabelecimentoID, *p.CodigoBalanca, p.ID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrDuplicateBarcode
+	}
+
+	return nil
+}
+
+// parseScaleBarcode lê uma etiqueta de balança no formato EAN-13
+// 2 CCCCC VVVVVV D: prefixo 2, código do produto na balança, valor (gramas
+// ou centavos) e dígito verificador.
+func parseScaleBarcode(code string) (string, int64, bool) {
+	if len(code) != 13 || code[0] != '2' {
+		return "", 0, false
+	}
+
+	if utils.GTINCheckDigit(code[:12]) != code[12] {
+		return "", 0, false
+	}
+
+	valor, err := strconv.ParseInt(code[6:12], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return code[1:6], valor, true
+}
+
+// FindProductByBarcode encontra o produto ativo pelo GTIN cadastrado,
+// comparado em GTIN-14, ou, não havendo, pela etiqueta de balança. OWNER
+// global pode informar o estabelecimento; os demais usam o próprio.
+func FindProductByBarcode(code string, estabelecimentoId int64, role, userId string) (*BarcodeLookup, error) {
+	if estabelecimentoId == 0 || !IsGlobalOwner(role, userId) {
+		var err error
+		estabelecimentoId, err = GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := CheckEstablishmentAccess(role, userId, estabelecimentoId)
+	if err != nil {
+		return nil, err
+	}
+
+	code = strings.TrimSpace(code)
+	lookup := BarcodeLookup{Codigo: code}
+
+	var product Product
+	row := db.DB.QueryRow(
+		"SELECT "+productColumns+` FROM products
+		WHERE deleted_at IS NULL AND estabelecimento_id = $1
+		AND id = (SELECT product_id FROM product_barcodes WHERE estabelecimento_id = $1 AND codigo = $2)`,
+		estabelecimentoId, utils.NormalizeGTIN(code),
+	)
+	err = scanProduct(row, &product)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		codigoBalanca, valor, ok := parseScaleBarcode(code)
+		if !ok {
+			return nil, err
+		}
+
+		row := db.DB.QueryRow(
+			"SELECT "+productColumns+" FROM products WHERE deleted_at IS NULL AND estabelecimento_id = $1 AND codigo_balanca = $2",
+			estabelecimentoId, codigoBalanca,
+		)
+		err = scanProduct(row, &product)
+		if err != nil {
+			return nil, err
+		}
+
+		err = lookup.applyScaleValue(&product, valor)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	products := []Product{product}
+	err = fillKitStock(db.DB, products)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup.Produto = &products[0]
+
+	return &lookup, nil
+}
+
+// applyScaleValue converte o valor da etiqueta em quantidade e valor total. O
+// valor do produto é por unidade de estoque.
+func (l *BarcodeLookup) applyScaleValue(p *Product, valor int64) error {
+	if p.ModoBalanca == nil {
+		return fmt.Errorf("produto %d sem modo de balança", p.ID)
+	}
+
+	var quantidade, total float64
+	precoVenda := p.Valor * p.FatorVenda
+
+	switch *p.ModoBalanca {
+	case ScaleModePeso:
+		quantidade = roundQuantity(float64(valor) / 1000)
+		total = roundMoney(quantidade * precoVenda)
+	case ScaleModePreco:
+		total = roundMoney(float64(valor) / 100)
+		if precoVenda <= 0 {
+			return ErrInvalidScaleCode
+		}
+		quantidade = roundQuantity(total / precoVenda)
+	default:
+		return ErrInvalidScaleCode
+	}
+
+	l.Balanca = true
+	l.Quantidade = &quantidade
+	l.ValorTotal = &total
+
+	return nil
+}
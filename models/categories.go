@@ -0,0 +1,253 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+var (
+	ErrInvalidCategory       = errors.New("categoria inválida para o estabelecimento do produto")
+	ErrInvalidParentCategory = errors.New("categoria pai inválida")
+	ErrCategoryHasChildren   = errors.New("a categoria possui subcategorias")
+)
+
+type Category struct {
+	ID                int64     `json:"id"`
+	Nome              string    `json:"nome" binding:"required"`
+	Descricao         string    `json:"descricao"`
+	ParentID          *int64    `json:"parent_id"`
+	EstabelecimentoID int64     `json:"estabelecimento_id" binding:"required"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type CategoryFilter struct {
+	ParentID string
+	Nome     string
+}
+
+const categoryColumns = "id, nome, descricao, parent_id, estabelecimento_id, created_at, updated_at"
+
+var CategorySortColumns = map[string]string{
+	"id":         "id",
+	"nome":       "nome",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+func scanCategory(row rowScanner, c *Category) error {
+	return row.Scan(&c.ID, &c.Nome, &c.Descricao, &c.ParentID, &c.EstabelecimentoID, &c.CreatedAt, &c.UpdatedAt)
+}
+
+// categoryTreeQuery devolve a subconsulta com o id da categoria informada no
+// parâmetro $param e os ids de todas as suas subcategorias, em qualquer nível.
+func categoryTreeQuery(param int) string {
+	return fmt.Sprintf(`WITH RECURSIVE category_tree AS (
+		SELECT id FROM categories WHERE id = $%d
+		UNION ALL
+		SELECT c.id FROM categories c JOIN category_tree t ON c.parent_id = t.id
+	) SELECT id FROM category_tree`, param)
+}
+
+// checkCategoryEstablishment garante que a categoria existe e pertence ao
+// estabelecimento informado.
+func checkCategoryEstablishment(tx *sql.Tx, categoryId, estabelecimentoId int64, invalid error) error {
+	var categoryEstabId int64
+	err := tx.QueryRow("SELECT estabelecimento_id FROM categories WHERE id = $1", categoryId).Scan(&categoryEstabId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return invalid
+	}
+	if err != nil {
+		return err
+	}
+
+	if categoryEstabId != estabelecimentoId {
+		return invalid
+	}
+
+	return nil
+}
+
+// checkProductCategory valida a categoria do produto, que é opcional.
+func checkProductCategory(tx *sql.Tx, p *Product) error {
+	if p.CategoriaID == nil {
+		return nil
+	}
+
+	return checkCategoryEstablishment(tx, *p.CategoriaID, p.EstabelecimentoID, ErrInvalidCategory)
+}
+
+// validateParent impede que a categoria aponte para um pai de outro
+// estabelecimento ou para si mesma e suas subcategorias, o que criaria um ciclo.
+func (c *Category) validateParent(tx *sql.Tx) error {
+	if c.ParentID == nil {
+		return nil
+	}
+
+	err := checkCategoryEstablishment(tx, *c.ParentID, c.EstabelecimentoID, ErrInvalidParentCategory)
+	if err != nil {
+		return err
+	}
+
+	if c.ID == 0 {
+		return nil
+	}
+
+	var cycle bool
+	err = tx.QueryRow("SELECT $2::integer IN ("+categoryTreeQuery(1)+")", c.ID, *c.ParentID).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+
+	if cycle {
+		return ErrInvalidParentCategory
+	}
+
+	return nil
+}
+
+func (c *Category) Save(tx *sql.Tx, role, userId string) error {
+	err := CheckEstablishmentAccess(role, userId, c.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	err = c.validateParent(tx)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO categories(nome, descricao, parent_id, estabelecimento_id)
+	VALUES($1, $2, $3, $4)
+	RETURNING id, created_at, updated_at`
+
+	return tx.QueryRow(query, c.Nome, c.Descricao, c.ParentID, c.EstabelecimentoID).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func GetAllCategories(role, userId string, filter CategoryFilter, params ListParams) ([]Category, ListResult, error) {
+	baseQuery := "SELECT " + categoryColumns + " FROM categories WHERE 1=1"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	if filter.ParentID == "null" {
+		baseQuery += " AND parent_id IS NULL"
+	} else if parentId, err := strconv.ParseInt(filter.ParentID, 10, 64); err == nil {
+		args = append(args, parentId)
+		baseQuery += fmt.Sprintf(" AND parent_id = $%d", len(args))
+	}
+	if filter.Nome != "" {
+		args = append(args, "%"+filter.Nome+"%")
+		baseQuery += fmt.Sprintf(" AND nome ILIKE $%d", len(args))
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var categories []Category
+
+	for rows.Next() {
+		var category Category
+		err := scanCategory(rows, &category)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		categories = append(categories, category)
+	}
+
+	fetched := len(categories)
+	if fetched > params.PerPage {
+		categories = categories[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(categories) > 0 {
+		lastID = categories[len(categories)-1].ID
+	}
+
+	return categories, params.result(total, fetched, lastID), nil
+}
+
+func GetCategory(id int64, role, userId string) (*Category, error) {
+	row := db.DB.QueryRow("SELECT "+categoryColumns+" FROM categories WHERE id = $1", id)
+
+	var category Category
+
+	err := scanCategory(row, &category)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, category.EstabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// Update altera nome, descrição e categoria pai. O estabelecimento da
+// categoria não muda, já que os produtos vinculados pertencem a ele.
+func (c *Category) Update(tx *sql.Tx) error {
+	err := c.validateParent(tx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE categories
+	SET nome = $1, descricao = $2, parent_id = $3, updated_at = $4
+	WHERE id = $5`
+
+	_, err = tx.Exec(query, c.Nome, c.Descricao, c.ParentID, c.UpdatedAt, c.ID)
+
+	return err
+}
+
+// Delete remove uma categoria sem subcategorias. Os produtos vinculados ficam
+// sem categoria.
+func (c *Category) Delete(tx *sql.Tx) error {
+	var hasChildren bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE parent_id = $1)", c.ID).Scan(&hasChildren)
+	if err != nil {
+		return err
+	}
+
+	if hasChildren {
+		return ErrCategoryHasChildren
+	}
+
+	_, err = tx.Exec("DELETE FROM categories WHERE id = $1", c.ID)
+
+	return err
+}
@@ -9,36 +9,71 @@ import (
 )
 
 type Establishment struct {
-	ID          int64     `json:"id"`
-	RazaoSocial string    `json:"razao_social" binding:"required"`
-	CPFCNPJ     string    `json:"cpf_cnpj" binding:"required"`
-	EnderecoID  int64     `json:"endereco_id"`
-	Endereco    Address   `json:"endereco" binding:"required"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                     int64      `json:"id"`
+	RazaoSocial            string     `json:"razao_social" binding:"required"`
+	CPFCNPJ                string     `json:"cpf_cnpj" binding:"required"`
+	PermiteEstoqueNegativo bool       `json:"permite_estoque_negativo"`
+	MetodoCusteio          string     `json:"metodo_custeio" binding:"omitempty,oneof=MEDIO PEPS"`
+	EnderecoID             int64      `json:"endereco_id"`
+	Endereco               Address    `json:"endereco" binding:"required"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
+	DeletedAt              *time.Time `json:"deleted_at,omitempty"`
 }
 
+// Save cadastra o estabelecimento com o seu local padrão. Sem método de
+// custeio informado, o estoque é avaliado pelo custo médio.
 func (e *Establishment) Save(tx *sql.Tx) error {
-	query := `INSERT INTO estabelecimentos(razao_social, cpf_cnpj, endereco_id)
-	VALUES($1, $2, $3)
+	if e.MetodoCusteio == "" {
+		e.MetodoCusteio = CostingMedio
+	}
+
+	query := `INSERT INTO estabelecimentos(razao_social, cpf_cnpj, endereco_id, permite_estoque_negativo, metodo_custeio)
+	VALUES($1, $2, $3, $4, $5)
 	RETURNING id, created_at, updated_at
 `
-	err := tx.QueryRow(query, e.RazaoSocial, e.CPFCNPJ, e.EnderecoID).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+	err := tx.QueryRow(query, e.RazaoSocial, e.CPFCNPJ, e.EnderecoID, e.PermiteEstoqueNegativo, e.MetodoCusteio).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
 
 	log.Println(err)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return createDefaultLocation(tx, e.ID)
+
+}
 
+var EstablishmentSortColumns = map[string]string{
+	"id":           "e.id",
+	"razao_social": "e.razao_social",
+	"cpf_cnpj":     "e.cpf_cnpj",
+	"created_at":   "e.created_at",
+	"updated_at":   "e.updated_at",
 }
 
-func GetAllEstablishments() ([]Establishment, error) {
-	query := `SELECT e.id, e.razao_social, e.cpf_cnpj, e.endereco_id, e.created_at, e.updated_at,
+func GetAllEstablishments(params ListParams, includeDeleted bool) ([]Establishment, ListResult, error) {
+	baseQuery := `SELECT e.id, e.razao_social, e.cpf_cnpj, e.permite_estoque_negativo, e.metodo_custeio, e.endereco_id, e.created_at, e.updated_at, e.deleted_at,
 a.logradouro, a.complemento, a.numero, a.bairro, a.cidade, a.uf, a.cep FROM estabelecimentos e
-JOIN enderecos a ON a.id = e.endereco_id`
+JOIN enderecos a ON a.id = e.endereco_id
+WHERE 1=1`
+
+	if !includeDeleted {
+		baseQuery += " AND e.deleted_at IS NULL"
+	}
 
-	rows, err := db.DB.Query(query)
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, nil, params, "e.id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery).Scan(&total)
 
 	if err != nil {
-		return nil, err
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
 	}
 
 	defer rows.Close()
@@ -50,27 +85,50 @@ JOIN enderecos a ON a.id = e.endereco_id`
 		var addr Address
 
 		err := rows.Scan(
-			&est.ID, &est.RazaoSocial, &est.CPFCNPJ, &est.EnderecoID, &est.CreatedAt, &est.UpdatedAt,
+			&est.ID, &est.RazaoSocial, &est.CPFCNPJ, &est.PermiteEstoqueNegativo, &est.MetodoCusteio, &est.EnderecoID, &est.CreatedAt, &est.UpdatedAt, &est.DeletedAt,
 			&addr.Logradouro, &addr.Complemento, &addr.Numero, &addr.Bairro, &addr.Cidade, &addr.UF, &addr.CEP,
 		)
 
 		if err != nil {
-			return nil, err
+			return nil, ListResult{}, err
 		}
 
 		est.Endereco = addr
 		establishments = append(establishments, est)
 	}
 
-	return establishments, nil
+	fetched := len(establishments)
+	if fetched > params.PerPage {
+		establishments = establishments[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(establishments) > 0 {
+		lastID = establishments[len(establishments)-1].ID
+	}
+
+	return establishments, params.result(total, fetched, lastID), nil
 }
 
 func GetEstablishmentByID(id int64) (*Establishment, error) {
-	query := `SELECT e.id, e.razao_social, e.cpf_cnpj, e.endereco_id, e.created_at, e.updated_at,
+	return findEstablishment(id, false)
+}
+
+// GetEstablishmentByIDWithDeleted também encontra estabelecimentos excluídos,
+// para restauração e expurgo.
+func GetEstablishmentByIDWithDeleted(id int64) (*Establishment, error) {
+	return findEstablishment(id, true)
+}
+
+func findEstablishment(id int64, includeDeleted bool) (*Establishment, error) {
+	query := `SELECT e.id, e.razao_social, e.cpf_cnpj, e.permite_estoque_negativo, e.metodo_custeio, e.endereco_id, e.created_at, e.updated_at, e.deleted_at,
        a.logradouro, a.complemento, a.numero, a.bairro, a.cidade, a.uf, a.cep
 FROM estabelecimentos e
 JOIN enderecos a ON a.id = e.endereco_id
 WHERE e.id = $1`
+	if !includeDeleted {
+		query += " AND e.deleted_at IS NULL"
+	}
 
 	row := db.DB.QueryRow(query, id)
 
@@ -78,7 +136,7 @@ WHERE e.id = $1`
 	var addr Address
 
 	err := row.Scan(
-		&est.ID, &est.RazaoSocial, &est.CPFCNPJ, &est.EnderecoID, &est.CreatedAt, &est.UpdatedAt,
+		&est.ID, &est.RazaoSocial, &est.CPFCNPJ, &est.PermiteEstoqueNegativo, &est.MetodoCusteio, &est.EnderecoID, &est.CreatedAt, &est.UpdatedAt, &est.DeletedAt,
 		&addr.Logradouro, &addr.Complemento, &addr.Numero, &addr.Bairro, &addr.Cidade, &addr.UF, &addr.CEP,
 	)
 
@@ -93,8 +151,8 @@ WHERE e.id = $1`
 
 func (e *Establishment) Update(tx *sql.Tx) error {
 	query := `UPDATE estabelecimentos
-	SET razao_social = $1, cpf_cnpj = $2, updated_at = $3, endereco_id = $4
-	WHERE id = $5`
+	SET razao_social = $1, cpf_cnpj = $2, updated_at = $3, endereco_id = $4, permite_estoque_negativo = $5, metodo_custeio = $6
+	WHERE id = $7`
 
 	stmt, err := tx.Prepare(query)
 
@@ -104,7 +162,7 @@ func (e *Establishment) Update(tx *sql.Tx) error {
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(e.RazaoSocial, e.CPFCNPJ, e.UpdatedAt, e.EnderecoID, e.ID)
+	_, err = stmt.Exec(e.RazaoSocial, e.CPFCNPJ, e.UpdatedAt, e.EnderecoID, e.PermiteEstoqueNegativo, e.MetodoCusteio, e.ID)
 
 	if err != nil {
 		return err
@@ -114,7 +172,60 @@ func (e *Establishment) Update(tx *sql.Tx) error {
 
 }
 
+// Delete faz a exclusão lógica do estabelecimento junto com seus usuários e
+// produtos ativos. Todos recebem o mesmo deleted_at, o que permite ao Restore
+// trazer de volta apenas o que foi excluído em cascata.
 func (e *Establishment) Delete(tx *sql.Tx) error {
+	var deletedAt time.Time
+	err := tx.QueryRow(
+		"UPDATE estabelecimentos SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at",
+		e.ID,
+	).Scan(&deletedAt)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE users SET deleted_at = $1 WHERE estabelecimento_id = $2 AND deleted_at IS NULL", deletedAt, e.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE refresh_tokens SET revoked_at = NOW()
+	WHERE revoked_at IS NULL AND user_id IN (SELECT id FROM users WHERE estabelecimento_id = $1)`, e.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE products SET deleted_at = $1 WHERE estabelecimento_id = $2 AND deleted_at IS NULL", deletedAt, e.ID)
+
+	return err
+}
+
+func (e *Establishment) Restore(tx *sql.Tx) error {
+	var deletedAt time.Time
+	err := tx.QueryRow("SELECT deleted_at FROM estabelecimentos WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE", e.ID).Scan(&deletedAt)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE users SET deleted_at = NULL, updated_at = NOW() WHERE estabelecimento_id = $1 AND deleted_at = $2", e.ID, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE products SET deleted_at = NULL, updated_at = NOW() WHERE estabelecimento_id = $1 AND deleted_at = $2", e.ID, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE estabelecimentos SET deleted_at = NULL, updated_at = NOW() WHERE id = $1", e.ID)
+
+	return err
+}
+
+func (e *Establishment) Purge(tx *sql.Tx) error {
 	query := "DELETE FROM estabelecimentos WHERE id = $1"
 
 	stmt, err := tx.Prepare(query)
@@ -131,5 +242,7 @@ func (e *Establishment) Delete(tx *sql.Tx) error {
 		return err
 	}
 
-	return nil
+	address := Address{ID: e.EnderecoID}
+
+	return address.Delete(tx)
 }
@@ -0,0 +1,505 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	InventoryCountAberta    = "ABERTA"
+	InventoryCountFechada   = "FECHADA"
+	InventoryCountCancelada = "CANCELADA"
+)
+
+var (
+	ErrInventoryCountAlreadyOpen   = errors.New("já existe uma contagem de inventário aberta para o estabelecimento")
+	ErrInvalidInventoryCountStatus = errors.New("a contagem de inventário não está em um status que permita essa operação")
+	ErrInvalidInventoryCountItem   = errors.New("produto não faz parte da contagem de inventário")
+)
+
+type InventoryCountItem struct {
+	ID                int64      `json:"id"`
+	ProductID         int64      `json:"product_id"`
+	ProductNome       string     `json:"product_nome"`
+	SKU               string     `json:"sku"`
+	EstoqueEsperado   float64    `json:"estoque_esperado"`
+	QuantidadeContada *float64   `json:"quantidade_contada"`
+	Diferenca         *float64   `json:"diferenca"`
+	ContadoPor        *int64     `json:"contado_por"`
+	ContadoEm         *time.Time `json:"contado_em"`
+	MovementID        *int64     `json:"movement_id"`
+}
+
+// InventoryCount é uma sessão de contagem física. Ao abrir, o estoque de cada
+// produto ativo do estabelecimento é congelado como quantidade esperada.
+type InventoryCount struct {
+	ID                int64                `json:"id"`
+	EstabelecimentoID int64                `json:"estabelecimento_id"`
+	Status            string               `json:"status"`
+	Observacao        string               `json:"observacao"`
+	AbertoPor         *int64               `json:"aberto_por"`
+	FechadoPor        *int64               `json:"fechado_por"`
+	FechadoEm         *time.Time           `json:"fechado_em"`
+	CanceladoEm       *time.Time           `json:"cancelado_em"`
+	Itens             []InventoryCountItem `json:"itens,omitempty"`
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+}
+
+type InventoryCountFilter struct {
+	Status string
+}
+
+// InventoryCountEntry é a quantidade contada de um SKU.
+type InventoryCountEntry struct {
+	SKU        string   `json:"sku" binding:"required"`
+	Quantidade *float64 `json:"quantidade" binding:"required,gte=0"`
+	ProductID  int64    `json:"product_id"`
+}
+
+// InventoryCountReport resume as divergências da contagem. Os valores usam o
+// preço de venda do produto.
+type InventoryCountReport struct {
+	InventoryCountID int64                `json:"inventory_count_id"`
+	Status           string               `json:"status"`
+	ItensTotal       int                  `json:"itens_total"`
+	ItensContados    int                  `json:"itens_contados"`
+	ItensNaoContados int                  `json:"itens_nao_contados"`
+	ItensDivergentes int                  `json:"itens_divergentes"`
+	ValorSobras      float64              `json:"valor_sobras"`
+	ValorFaltas      float64              `json:"valor_faltas"`
+	ValorLiquido     float64              `json:"valor_liquido"`
+	Divergencias     []InventoryCountItem `json:"divergencias"`
+	NaoContados      []InventoryCountItem `json:"nao_contados"`
+}
+
+const inventoryCountColumns = `id, estabelecimento_id, status, observacao, aberto_por, fechado_por, fechado_em, cancelado_em,
+	created_at, updated_at`
+
+var InventoryCountSortColumns = map[string]string{
+	"id":         "id",
+	"status":     "status",
+	"created_at": "created_at",
+}
+
+func scanInventoryCount(row rowScanner, c *InventoryCount) error {
+	return row.Scan(
+		&c.ID, &c.EstabelecimentoID, &c.Status, &c.Observacao, &c.AbertoPor, &c.FechadoPor, &c.FechadoEm, &c.CanceladoEm,
+		&c.CreatedAt, &c.UpdatedAt,
+	)
+}
+
+// Save abre a contagem e congela o estoque atual dos produtos ativos, exceto
+// kits e produtos pai, cujo estoque vem de outros produtos. O estabelecimento é
+// bloqueado para que duas aberturas simultâneas não passem pela verificação de
+// contagem já aberta.
+func (c *InventoryCount) Save(tx *sql.Tx, role string, userId int64) error {
+	userIdStr := fmt.Sprintf("%d", userId)
+
+	if c.EstabelecimentoID == 0 {
+		estabelecimentoId, err := GetUserEstablishmentID(userIdStr)
+		if err != nil {
+			return err
+		}
+		c.EstabelecimentoID = estabelecimentoId
+	}
+
+	err := CheckEstablishmentAccess(role, userIdStr, c.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	var estabelecimentoId int64
+	err = tx.QueryRow("SELECT id FROM estabelecimentos WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", c.EstabelecimentoID).Scan(&estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	var open bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM inventory_counts WHERE estabelecimento_id = $1 AND status = $2)",
+		c.EstabelecimentoID, InventoryCountAberta,
+	).Scan(&open)
+	if err != nil {
+		return err
+	}
+
+	if open {
+		return ErrInventoryCountAlreadyOpen
+	}
+
+	c.Status = InventoryCountAberta
+	c.AbertoPor = &userId
+
+	query := `INSERT INTO inventory_counts(estabelecimento_id, status, observacao, aberto_por)
+	VALUES($1, $2, $3, $4)
+	RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(query, c.EstabelecimentoID, c.Status, c.Observacao, userId).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO inventory_count_items(inventory_count_id, product_id, estoque_esperado)
+	SELECT $1, id, estoque FROM products
+	WHERE estabelecimento_id = $2 AND deleted_at IS NULL AND tipo <> 'KIT'
+	AND NOT EXISTS(SELECT 1 FROM products v WHERE v.parent_id = products.id)`, c.ID, c.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	c.Itens, err = getInventoryCountItems(tx, c.ID)
+
+	return err
+}
+
+func getInventoryCountItems(q queryer, inventoryCountId int64) ([]InventoryCountItem, error) {
+	rows, err := q.Query(`SELECT i.id, i.product_id, p.nome, p.sku, i.estoque_esperado, i.quantidade_contada,
+		i.quantidade_contada - i.estoque_esperado, i.contado_por, i.contado_em, i.movement_id
+	FROM inventory_count_items i
+	JOIN products p ON p.id = i.product_id
+	WHERE i.inventory_count_id = $1
+	ORDER BY p.nome, i.id`, inventoryCountId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []InventoryCountItem{}
+
+	for rows.Next() {
+		var item InventoryCountItem
+		err := rows.Scan(
+			&item.ID, &item.ProductID, &item.ProductNome, &item.SKU, &item.EstoqueEsperado, &item.QuantidadeContada,
+			&item.Diferenca, &item.ContadoPor, &item.ContadoEm, &item.MovementID,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func GetAllInventoryCounts(role, userId string, filter InventoryCountFilter, params ListParams) ([]InventoryCount, ListResult, error) {
+	baseQuery := "SELECT " + inventoryCountColumns + " FROM inventory_counts WHERE 1=1"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		baseQuery += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var counts []InventoryCount
+
+	for rows.Next() {
+		var count InventoryCount
+		err := scanInventoryCount(rows, &count)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	fetched := len(counts)
+	if fetched > params.PerPage {
+		counts = counts[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(counts) > 0 {
+		lastID = counts[len(counts)-1].ID
+	}
+
+	return counts, params.result(total, fetched, lastID), nil
+}
+
+func GetInventoryCount(id int64, role, userId string) (*InventoryCount, error) {
+	row := db.DB.QueryRow("SELECT "+inventoryCountColumns+" FROM inventory_counts WHERE id = $1", id)
+
+	var count InventoryCount
+
+	err := scanInventoryCount(row, &count)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, count.EstabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	count.Itens, err = getInventoryCountItems(db.DB, count.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &count, nil
+}
+
+func (c *InventoryCount) lock(tx *sql.Tx) error {
+	row := tx.QueryRow("SELECT "+inventoryCountColumns+" FROM inventory_counts WHERE id = $1 FOR UPDATE", c.ID)
+	return scanInventoryCount(row, c)
+}
+
+// Submit registra as quantidades contadas. A sessão é bloqueada em modo
+// compartilhado, então vários contadores podem enviar ao mesmo tempo enquanto o
+// fechamento espera os envios em andamento. Um SKU contado de novo tem a
+// quantidade substituída.
+func (c *InventoryCount) Submit(tx *sql.Tx, role string, userId int64, entries []InventoryCountEntry) error {
+	row := tx.QueryRow("SELECT "+inventoryCountColumns+" FROM inventory_counts WHERE id = $1 FOR SHARE", c.ID)
+	err := scanInventoryCount(row, c)
+	if err != nil {
+		return err
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), c.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	if c.Status != InventoryCountAberta {
+		return ErrInvalidInventoryCountStatus
+	}
+
+	query := `UPDATE inventory_count_items i
+	SET quantidade_contada = $1, contado_por = $2, contado_em = NOW()
+	FROM products p
+	WHERE p.id = i.product_id AND i.inventory_count_id = $3 AND p.sku = $4
+	RETURNING i.product_id, p.unidade`
+
+	for i := range entries {
+		entry := &entries[i]
+		quantidade := roundQuantity(*entry.Quantidade)
+		entry.Quantidade = &quantidade
+
+		var unidade string
+		err := tx.QueryRow(query, quantidade, userId, c.ID, entry.SKU).Scan(&entry.ProductID, &unidade)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidInventoryCountItem
+		}
+		if err != nil {
+			return err
+		}
+
+		err = checkUnitQuantity(unidade, quantidade)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close aplica um ajuste de estoque para cada item contado com divergência e
+// encerra a sessão. O ajuste é a diferença entre o contado e o congelado, de
+// modo que vendas e entradas feitas durante a contagem são preservadas. Itens
+// não contados ficam como estão.
+func (c *InventoryCount) Close(tx *sql.Tx, role string, userId int64) error {
+	err := c.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if c.Status != InventoryCountAberta {
+		return ErrInvalidInventoryCountStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), c.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	var permiteNegativo bool
+	err = tx.QueryRow("SELECT permite_estoque_negativo FROM estabelecimentos WHERE id = $1", c.EstabelecimentoID).Scan(&permiteNegativo)
+	if err != nil {
+		return err
+	}
+
+	items, err := getInventoryCountItems(tx, c.ID)
+	if err != nil {
+		return err
+	}
+
+	productIds := make([]int64, 0, len(items))
+	for _, item := range items {
+		productIds = append(productIds, item.ProductID)
+	}
+
+	err = lockProducts(tx, productIds)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.Diferenca == nil || roundQuantity(*item.Diferenca) == 0 {
+			continue
+		}
+
+		movement := StockMovement{
+			ProductID:              item.ProductID,
+			Tipo:                   MovementAjuste,
+			Quantidade:             roundQuantity(*item.Diferenca),
+			Motivo:                 fmt.Sprintf("Inventário #%d", c.ID),
+			UserID:                 userId,
+			PermiteEstoqueNegativo: permiteNegativo,
+		}
+
+		err = movement.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		item.MovementID = &movement.ID
+
+		_, err = tx.Exec("UPDATE inventory_count_items SET movement_id = $1 WHERE id = $2", movement.ID, item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	c.Status = InventoryCountFechada
+	c.FechadoPor = &userId
+	c.FechadoEm = &now
+	c.UpdatedAt = now
+	c.Itens = items
+
+	_, err = tx.Exec(
+		"UPDATE inventory_counts SET status = $1, fechado_por = $2, fechado_em = $3, updated_at = $3 WHERE id = $4",
+		c.Status, userId, now, c.ID,
+	)
+
+	return err
+}
+
+// Cancel descarta a sessão sem alterar o estoque.
+func (c *InventoryCount) Cancel(tx *sql.Tx, role string, userId int64) error {
+	err := c.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if c.Status != InventoryCountAberta {
+		return ErrInvalidInventoryCountStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), c.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	c.Status = InventoryCountCancelada
+	c.CanceladoEm = &now
+	c.UpdatedAt = now
+
+	_, err = tx.Exec(
+		"UPDATE inventory_counts SET status = $1, cancelado_em = $2, updated_at = $2 WHERE id = $3",
+		c.Status, now, c.ID,
+	)
+
+	return err
+}
+
+// Report monta o relatório de divergências a partir dos itens carregados.
+// Enquanto a sessão está aberta ele serve de prévia do fechamento.
+func (c *InventoryCount) Report() (*InventoryCountReport, error) {
+	report := InventoryCountReport{
+		InventoryCountID: c.ID,
+		Status:           c.Status,
+		ItensTotal:       len(c.Itens),
+		Divergencias:     []InventoryCountItem{},
+		NaoContados:      []InventoryCountItem{},
+	}
+
+	valores := map[int64]float64{}
+	rows, err := db.DB.Query(`SELECT p.id, p.valor
+	FROM inventory_count_items i
+	JOIN products p ON p.id = i.product_id
+	WHERE i.inventory_count_id = $1`, c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var productId int64
+		var valor float64
+		err := rows.Scan(&productId, &valor)
+		if err != nil {
+			return nil, err
+		}
+		valores[productId] = valor
+	}
+
+	for _, item := range c.Itens {
+		if item.QuantidadeContada == nil {
+			report.ItensNaoContados++
+			report.NaoContados = append(report.NaoContados, item)
+			continue
+		}
+
+		report.ItensContados++
+
+		diferenca := roundQuantity(*item.Diferenca)
+		if diferenca == 0 {
+			continue
+		}
+
+		report.ItensDivergentes++
+		report.Divergencias = append(report.Divergencias, item)
+
+		valor := roundMoney(diferenca * valores[item.ProductID])
+		if valor > 0 {
+			report.ValorSobras = roundMoney(report.ValorSobras + valor)
+		} else {
+			report.ValorFaltas = roundMoney(report.ValorFaltas - valor)
+		}
+	}
+
+	report.ValorLiquido = roundMoney(report.ValorSobras - report.ValorFaltas)
+
+	return &report, nil
+}
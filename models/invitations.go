@@ -0,0 +1,169 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
+)
+
+const InvitationTTL = time.Hour * 24 * 7
+
+var (
+	ErrInvalidInvitation  = errors.New("convite inválido, expirado ou já utilizado")
+	ErrInvitationRole     = errors.New("papel não permitido para o convite")
+	ErrInvitationExpired  = errors.New("convite expirado")
+	ErrInvitationAccepted = errors.New("convite já utilizado")
+)
+
+type Invitation struct {
+	ID                int64      `json:"id"`
+	Email             string     `json:"email" binding:"required,email"`
+	Role              string     `json:"role" binding:"required,oneof=OWNER MANAGER SELLER"`
+	EstabelecimentoID int64      `json:"estabelecimento_id"`
+	Token             string     `json:"token,omitempty"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	AcceptedAt        *time.Time `json:"accepted_at"`
+	CreatedBy         *int64     `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+type AcceptInvitationInput struct {
+	Token     string `json:"token" binding:"required"`
+	Nome      string `json:"nome" binding:"required"`
+	Sobrenome string `json:"sobrenome" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+}
+
+// Save cria o convite. MANAGERs não podem conceder o papel de OWNER e, sem
+// estabelecimento_id, o convite vale para o estabelecimento de quem convida.
+// Quem entra por convite nunca recebe acesso global. O token em texto puro só
+// é devolvido nesta resposta; no banco fica apenas o hash.
+func (i *Invitation) Save(role string, userId int64) error {
+	userIdStr := fmt.Sprintf("%d", userId)
+
+	if role != "OWNER" && i.Role == "OWNER" {
+		return ErrInvitationRole
+	}
+
+	if i.EstabelecimentoID == 0 {
+		estabelecimentoId, err := GetUserEstablishmentID(userIdStr)
+		if err != nil {
+			return err
+		}
+
+		i.EstabelecimentoID = estabelecimentoId
+	}
+
+	err := CheckEstablishmentAccess(role, userIdStr, i.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+
+	i.Token = token
+	i.ExpiresAt = time.Now().Add(InvitationTTL)
+	i.CreatedBy = &userId
+
+	query := `INSERT INTO invitations(email, role, estabelecimento_id, token_hash, expires_at, created_by)
+	VALUES($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+
+	return db.DB.QueryRow(query, i.Email, i.Role, i.EstabelecimentoID, utils.HashToken(token), i.ExpiresAt, userId).Scan(&i.ID, &i.CreatedAt)
+}
+
+func GetAllInvitations(role, userId string) ([]Invitation, error) {
+	query := "SELECT id, email, role, estabelecimento_id, expires_at, accepted_at, created_by, created_at FROM invitations"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, err
+		}
+
+		query += " WHERE estabelecimento_id = $1"
+		args = append(args, estabelecimentoId)
+	}
+
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := db.DB.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var invitations []Invitation
+
+	for rows.Next() {
+		var invitation Invitation
+		err := rows.Scan(&invitation.ID, &invitation.Email, &invitation.Role, &invitation.EstabelecimentoID, &invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.CreatedBy, &invitation.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, nil
+}
+
+// Accept consome o convite e cria o usuário com o email, o papel e o
+// estabelecimento definidos nele, na transação informada.
+func (input *AcceptInvitationInput) Accept(tx *sql.Tx) (*User, error) {
+	var invitation Invitation
+
+	err := tx.QueryRow(`SELECT id, email, role, estabelecimento_id, expires_at, accepted_at
+	FROM invitations
+	WHERE token_hash = $1
+	FOR UPDATE`, utils.HashToken(input.Token)).Scan(
+		&invitation.ID, &invitation.Email, &invitation.Role, &invitation.EstabelecimentoID, &invitation.ExpiresAt, &invitation.AcceptedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidInvitation
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.AcceptedAt != nil {
+		return nil, ErrInvitationAccepted
+	}
+
+	if time.Now().After(invitation.ExpiresAt) {
+		return nil, ErrInvitationExpired
+	}
+
+	user := User{
+		Nome:              input.Nome,
+		Sobrenome:         input.Sobrenome,
+		Email:             invitation.Email,
+		Password:          input.Password,
+		Role:              invitation.Role,
+		EstabelecimentoID: invitation.EstabelecimentoID,
+	}
+
+	err = user.Save(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("UPDATE invitations SET accepted_at = NOW() WHERE id = $1", invitation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
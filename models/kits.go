@@ -0,0 +1,459 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/lib/pq"
+)
+
+const (
+	ProductSimples = "SIMPLES"
+	ProductKit     = "KIT"
+)
+
+var (
+	ErrNotKit              = errors.New("o produto não é um kit")
+	ErrInvalidKitComponent = errors.New("componente inválido para o kit")
+	ErrCircularKit         = errors.New("o componente contém o próprio kit")
+	ErrKitLot              = errors.New("kits não têm lotes; os lotes são controlados nos componentes")
+)
+
+// KitComponent é um item da lista de materiais de um kit: o produto
+// componente e quanto dele compõe uma unidade do kit. Disponivel desconta do
+// estoque o que está reservado.
+type KitComponent struct {
+	ComponentID int64   `json:"component_id" binding:"required"`
+	ProductNome string  `json:"product_nome"`
+	SKU         string  `json:"sku"`
+	Quantidade  float64 `json:"quantidade" binding:"required,gt=0"`
+	Estoque     float64 `json:"estoque"`
+	Disponivel  float64 `json:"disponivel"`
+	tipo        string
+}
+
+func getKitComponents(q queryer, kitId int64) ([]KitComponent, error) {
+	rows, err := q.Query(`SELECT k.component_id, p.nome, p.sku, k.quantidade, p.estoque, p.estoque - p.estoque_reservado, p.tipo
+	FROM product_kit_components k
+	JOIN products p ON p.id = k.component_id
+	WHERE k.kit_id = $1
+	ORDER BY k.component_id`, kitId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	components := []KitComponent{}
+
+	for rows.Next() {
+		var component KitComponent
+		err := rows.Scan(&component.ComponentID, &component.ProductNome, &component.SKU, &component.Quantidade, &component.Estoque, &component.Disponivel, &component.tipo)
+
+		if err != nil {
+			return nil, err
+		}
+
+		components = append(components, component)
+	}
+
+	return components, nil
+}
+
+// GetKitComponents lista os componentes do kit, com o estoque disponível de
+// cada um.
+func GetKitComponents(kitId int64) ([]KitComponent, error) {
+	components, err := getKitComponents(db.DB, kitId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range components {
+		if components[i].tipo == ProductKit {
+			components[i].Estoque, err = kitAvailable(db.DB, components[i].ComponentID)
+			if err != nil {
+				return nil, err
+			}
+			components[i].Disponivel = components[i].Estoque
+		}
+	}
+
+	return components, nil
+}
+
+// kitAvailable calcula quantos kits completos podem ser montados com o estoque
+// disponível dos componentes, já descontadas as reservas, incluindo kits dentro
+// de kits.
+func kitAvailable(q queryer, kitId int64) (float64, error) {
+	components := func(kitId int64) ([]KitComponent, error) {
+		return getKitComponents(q, kitId)
+	}
+
+	return kitAvailableFrom(components, kitId, map[int64]bool{})
+}
+
+// kitAvailableFrom percorre a árvore, lendo os componentes de cada kit por
+// components, e guarda os kits do caminho atual para falhar com
+// ErrCircularKit em vez de recursar indefinidamente caso um ciclo tenha
+// chegado ao banco.
+func kitAvailableFrom(components func(kitId int64) ([]KitComponent, error), kitId int64, path map[int64]bool) (float64, error) {
+	if path[kitId] {
+		return 0, ErrCircularKit
+	}
+	path[kitId] = true
+	defer delete(path, kitId)
+
+	kitComponents, err := components(kitId)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(kitComponents) == 0 {
+		return 0, nil
+	}
+
+	disponivel := math.Inf(1)
+
+	for _, component := range kitComponents {
+		estoque := component.Disponivel
+		if component.tipo == ProductKit {
+			estoque, err = kitAvailableFrom(components, component.ComponentID, path)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		kits := math.Floor(roundQuantity(estoque / component.Quantidade))
+		if kits < disponivel {
+			disponivel = kits
+		}
+	}
+
+	if disponivel < 0 {
+		return 0, nil
+	}
+
+	return disponivel, nil
+}
+
+// fillKitStock troca o estoque dos kits da lista pelo disponível calculado a
+// partir dos componentes. Kits não têm reservas próprias.
+func fillKitStock(q queryer, products []Product) error {
+	for i := range products {
+		if products[i].Tipo != ProductKit {
+			continue
+		}
+
+		estoque, err := kitAvailable(q, products[i].ID)
+		if err != nil {
+			return err
+		}
+
+		products[i].Estoque = estoque
+		products[i].Disponivel = estoque
+	}
+
+	return nil
+}
+
+// kitTreeLock é a chave das travas consultivas da lista de materiais; a
+// segunda chave é o estabelecimento.
+const kitTreeLock = 17
+
+// lockKitTree trava a lista de materiais dos kits do estabelecimento até o fim
+// da transação. A edição pede a trava exclusiva, o que serializa as edições e
+// torna a verificação de ciclos confiável; as movimentações pedem a
+// compartilhada, para que a árvore não mude depois de bloqueada.
+func lockKitTree(tx *sql.Tx, estabelecimentoId int64, exclusive bool) error {
+	query := "SELECT pg_advisory_xact_lock_shared($1, $2)"
+	if exclusive {
+		query = "SELECT pg_advisory_xact_lock($1, $2)"
+	}
+
+	_, err := tx.Exec(query, kitTreeLock, estabelecimentoId)
+
+	return err
+}
+
+// lockProducts bloqueia os produtos e, nos kits, toda a árvore de componentes,
+// sempre em ordem de id. Quem movimenta estoque chama antes de qualquer outro
+// FOR UPDATE de produto, o que evita deadlocks entre vendas, transferências,
+// recebimentos e movimentações de kits.
+func lockProducts(tx *sql.Tx, productIds []int64) error {
+	rows, err := tx.Query("SELECT DISTINCT estabelecimento_id FROM products WHERE id = ANY($1) ORDER BY estabelecimento_id", pq.Array(productIds))
+	if err != nil {
+		return err
+	}
+
+	var estabelecimentos []int64
+	for rows.Next() {
+		var estabelecimentoId int64
+		err := rows.Scan(&estabelecimentoId)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		estabelecimentos = append(estabelecimentos, estabelecimentoId)
+	}
+	rows.Close()
+
+	for _, estabelecimentoId := range estabelecimentos {
+		err = lockKitTree(tx, estabelecimentoId, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`WITH RECURSIVE tree AS (
+		SELECT id FROM products WHERE id = ANY($1)
+		UNION
+		SELECT k.component_id FROM product_kit_components k JOIN tree t ON k.kit_id = t.id
+	)
+	SELECT p.id FROM products p JOIN tree t ON t.id = p.id ORDER BY p.id FOR UPDATE OF p`, pq.Array(productIds))
+
+	return err
+}
+
+// SetKitComponents substitui a lista de materiais do kit. Os componentes
+// precisam ser produtos ativos do mesmo estabelecimento e nenhum deles pode
+// conter, direta ou indiretamente, o próprio kit. As edições do
+// estabelecimento são serializadas pela trava da lista de materiais, então
+// duas edições simultâneas não conseguem fechar um ciclo.
+func SetKitComponents(tx *sql.Tx, kitId int64, components []KitComponent) error {
+	var tipo string
+	var estabelecimentoId int64
+	err := tx.QueryRow("SELECT tipo, estabelecimento_id FROM products WHERE id = $1 AND deleted_at IS NULL", kitId).Scan(&tipo, &estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	err = lockKitTree(tx, estabelecimentoId, true)
+	if err != nil {
+		return err
+	}
+
+	if tipo != ProductKit {
+		return ErrNotKit
+	}
+
+	_, err = tx.Exec("DELETE FROM product_kit_components WHERE kit_id = $1", kitId)
+	if err != nil {
+		return err
+	}
+
+	seen := map[int64]bool{}
+
+	for i := range components {
+		component := &components[i]
+
+		if component.ComponentID == kitId || seen[component.ComponentID] {
+			return ErrInvalidKitComponent
+		}
+		seen[component.ComponentID] = true
+
+		var componentEstabId int64
+		var unidade string
+		var hasVariants bool
+		err := tx.QueryRow(
+			`SELECT nome, sku, estoque, tipo, unidade, estabelecimento_id, EXISTS(SELECT 1 FROM products v WHERE v.parent_id = products.id)
+			FROM products WHERE id = $1 AND deleted_at IS NULL`,
+			component.ComponentID,
+		).Scan(&component.ProductNome, &component.SKU, &component.Estoque, &component.tipo, &unidade, &componentEstabId, &hasVariants)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidKitComponent
+		}
+		if err != nil {
+			return err
+		}
+
+		if componentEstabId != estabelecimentoId || hasVariants {
+			return ErrInvalidKitComponent
+		}
+
+		var circular bool
+		err = tx.QueryRow(`WITH RECURSIVE tree AS (
+			SELECT component_id FROM product_kit_components WHERE kit_id = $1
+			UNION
+			SELECT k.component_id FROM product_kit_components k JOIN tree t ON k.kit_id = t.component_id
+		)
+		SELECT EXISTS(SELECT 1 FROM tree WHERE component_id = $2)`, component.ComponentID, kitId).Scan(&circular)
+		if err != nil {
+			return err
+		}
+
+		if circular {
+			return ErrCircularKit
+		}
+
+		component.Quantidade = roundQuantity(component.Quantidade)
+		if component.Quantidade <= 0 {
+			return ErrInvalidQuantity
+		}
+
+		err = checkUnitQuantity(unidade, component.Quantidade)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO product_kit_components(kit_id, component_id, quantidade) VALUES($1, $2, $3)",
+			kitId, component.ComponentID, component.Quantidade,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveKit registra a movimentação de um kit desdobrando-a em uma movimentação
+// por componente, todas na mesma transação. O kit não tem estoque próprio:
+// anterior e posterior registram quantos kits podiam ser montados.
+func (m *StockMovement) saveKit(tx *sql.Tx) error {
+	if m.Lote != "" {
+		return ErrKitLot
+	}
+
+	// O custo é dos componentes, que entram pelo custo vigente de cada um.
+	m.CustoUnitario = nil
+
+	components, err := getKitComponents(tx, m.ProductID)
+	if err != nil {
+		return err
+	}
+
+	if len(components) == 0 {
+		return ErrInvalidKitComponent
+	}
+
+	m.EstoqueAnterior, err = kitAvailable(tx, m.ProductID)
+	if err != nil {
+		return err
+	}
+	m.EstoquePosterior = m.EstoqueAnterior
+
+	err = m.insert(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, component := range components {
+		movement := StockMovement{
+			ProductID:              component.ComponentID,
+			Tipo:                   m.Tipo,
+			Quantidade:             roundQuantity(m.Quantidade * component.Quantidade),
+			Motivo:                 m.Motivo,
+			UserID:                 m.UserID,
+			KitMovementID:          &m.ID,
+			PermiteEstoqueNegativo: m.PermiteEstoqueNegativo,
+		}
+
+		if movement.Quantidade == 0 {
+			continue
+		}
+
+		err = movement.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		m.Componentes = append(m.Componentes, movement)
+	}
+
+	return m.finishKit(tx)
+}
+
+// finishKit grava quantos kits podem ser montados após a movimentação dos
+// componentes.
+func (m *StockMovement) finishKit(tx *sql.Tx) error {
+	var err error
+	m.EstoquePosterior, err = kitAvailable(tx, m.ProductID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE stock_movements SET estoque_posterior = $1 WHERE id = $2", m.EstoquePosterior, m.ID)
+
+	return err
+}
+
+// restockKitFromMovement devolve ao estoque parte de uma saída de kit,
+// recompondo cada componente a partir da movimentação que o consumiu.
+func restockKitFromMovement(tx *sql.Tx, movementId, kitId int64, quantidade float64, motivo string, userId int64) ([]StockMovement, error) {
+	var kitQuantidade float64
+	err := tx.QueryRow("SELECT ABS(quantidade) FROM stock_movements WHERE id = $1", movementId).Scan(&kitQuantidade)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT id, product_id, ABS(quantidade) FROM stock_movements WHERE kit_movement_id = $1 ORDER BY product_id", movementId)
+	if err != nil {
+		return nil, err
+	}
+
+	var consumed []StockMovement
+	for rows.Next() {
+		var movement StockMovement
+		err := rows.Scan(&movement.ID, &movement.ProductID, &movement.Quantidade)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		consumed = append(consumed, movement)
+	}
+	rows.Close()
+
+	kit := StockMovement{
+		ProductID:  kitId,
+		Tipo:       MovementEntrada,
+		Quantidade: roundQuantity(quantidade),
+		Motivo:     motivo,
+		UserID:     userId,
+	}
+
+	kit.EstoqueAnterior, err = kitAvailable(tx, kitId)
+	if err != nil {
+		return nil, err
+	}
+	kit.EstoquePosterior = kit.EstoqueAnterior
+
+	err = kit.insert(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, component := range consumed {
+		devolvido := roundQuantity(component.Quantidade * quantidade / kitQuantidade)
+		if devolvido <= 0 {
+			continue
+		}
+
+		movements, err := restockFromMovement(tx, component.ID, component.ProductID, devolvido, motivo, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range movements {
+			movements[i].KitMovementID = &kit.ID
+
+			_, err = tx.Exec("UPDATE stock_movements SET kit_movement_id = $1 WHERE id = $2", kit.ID, movements[i].ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		kit.Componentes = append(kit.Componentes, movements...)
+	}
+
+	err = kit.finishKit(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []StockMovement{kit}, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKitAvailableFrom(t *testing.T) {
+	simples := func(id int64, quantidade, estoque, disponivel float64) KitComponent {
+		return KitComponent{ComponentID: id, Quantidade: quantidade, Estoque: estoque, Disponivel: disponivel, tipo: ProductSimples}
+	}
+	kit := func(id int64, quantidade float64) KitComponent {
+		return KitComponent{ComponentID: id, Quantidade: quantidade, tipo: ProductKit}
+	}
+
+	tests := []struct {
+		name       string
+		kitId      int64
+		bom        map[int64][]KitComponent
+		disponivel float64
+		wantErr    error
+	}{
+		{
+			name:       "limitado pelo componente mais escasso",
+			kitId:      10,
+			bom:        map[int64][]KitComponent{10: {simples(1, 2, 9, 9), simples(2, 3, 7, 7)}},
+			disponivel: 2,
+		},
+		{
+			name:  "kit dentro de kit",
+			kitId: 10,
+			bom: map[int64][]KitComponent{
+				10: {simples(1, 2, 9, 9), kit(11, 1)},
+				11: {simples(2, 3, 10, 10), simples(3, 0.5, 1.2, 1.2)},
+			},
+			disponivel: 2,
+		},
+		{
+			name:       "desconta as reservas dos componentes",
+			kitId:      10,
+			bom:        map[int64][]KitComponent{10: {simples(1, 2, 10, 4)}},
+			disponivel: 2,
+		},
+		{
+			name:       "componente com saldo negativo",
+			kitId:      10,
+			bom:        map[int64][]KitComponent{10: {simples(1, 1, -3, -3)}},
+			disponivel: 0,
+		},
+		{
+			name:       "kit sem componentes",
+			kitId:      10,
+			bom:        map[int64][]KitComponent{},
+			disponivel: 0,
+		},
+		{
+			name:  "ciclo A→B→A",
+			kitId: 20,
+			bom: map[int64][]KitComponent{
+				20: {kit(21, 1)},
+				21: {kit(20, 1)},
+			},
+			wantErr: ErrCircularKit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			components := func(kitId int64) ([]KitComponent, error) {
+				return tt.bom[kitId], nil
+			}
+
+			disponivel, err := kitAvailableFrom(components, tt.kitId, map[int64]bool{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("kitAvailableFrom() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if disponivel != tt.disponivel {
+				t.Errorf("kitAvailableFrom() = %v, want %v", disponivel, tt.disponivel)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 100
+)
+
+var (
+	ErrInvalidSortField = errors.New("campo de ordenação inválido")
+	ErrInvalidCursor    = errors.New("cursor inválido")
+	ErrCursorWithSort   = errors.New("a paginação por cursor não pode ser combinada com ordenação personalizada")
+)
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// ListParams descreve a página solicitada por um endpoint de listagem. Quando
+// After é informado a paginação é feita por cursor (id) e Page é ignorado.
+type ListParams struct {
+	Page    int
+	PerPage int
+	After   int64
+	Sort    []SortField
+}
+
+type ListResult struct {
+	Total      int64
+	HasMore    bool
+	NextCursor string
+}
+
+// ParseSort converte "campo,-campo" em colunas SQL usando a whitelist
+// informada, que mapeia o nome público do campo para a coluna.
+func ParseSort(sort string, allowed map[string]string) ([]SortField, error) {
+	if sort == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(part, "-")
+
+		column, ok := allowed[name]
+		if !ok {
+			return nil, ErrInvalidSortField
+		}
+
+		fields = append(fields, SortField{Column: column, Desc: desc})
+	}
+
+	return fields, nil
+}
+
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func DecodeCursor(cursor string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(string(decoded), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return id, nil
+}
+
+func (p ListParams) orderBy(idColumn string) string {
+	var parts []string
+
+	for _, field := range p.Sort {
+		direction := "ASC"
+		if field.Desc {
+			direction = "DESC"
+		}
+		parts = append(parts, field.Column+" "+direction)
+	}
+
+	parts = append(parts, idColumn+" ASC")
+
+	return strings.Join(parts, ", ")
+}
+
+// buildListQueries recebe a consulta já filtrada (com cláusula WHERE) e
+// devolve a consulta de contagem total e a consulta da página. A página busca
+// uma linha a mais para indicar se existem mais resultados.
+func buildListQueries(baseQuery string, args []interface{}, params ListParams, idColumn string) (string, string, []interface{}) {
+	countQuery := "SELECT COUNT(*) FROM (" + baseQuery + ") AS filtered"
+
+	pageArgs := append([]interface{}{}, args...)
+	pageQuery := baseQuery
+
+	if params.After > 0 {
+		pageArgs = append(pageArgs, params.After)
+		pageQuery += fmt.Sprintf(" AND %s > $%d", idColumn, len(pageArgs))
+	}
+
+	pageQuery += " ORDER BY " + params.orderBy(idColumn)
+
+	pageArgs = append(pageArgs, params.PerPage+1)
+	pageQuery += fmt.Sprintf(" LIMIT $%d", len(pageArgs))
+
+	if params.After == 0 {
+		pageArgs = append(pageArgs, (params.Page-1)*params.PerPage)
+		pageQuery += fmt.Sprintf(" OFFSET $%d", len(pageArgs))
+	}
+
+	return countQuery, pageQuery, pageArgs
+}
+
+// result monta o resultado da listagem. O cursor só é gerado na ordenação
+// padrão, em que o id do último item delimita a próxima página.
+func (p ListParams) result(total int64, fetched int, lastID int64) ListResult {
+	result := ListResult{Total: total, HasMore: fetched > p.PerPage}
+
+	if result.HasMore && len(p.Sort) == 0 {
+		result.NextCursor = EncodeCursor(lastID)
+	}
+
+	return result
+}
@@ -0,0 +1,365 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	PriceOriginBase  = "BASE"
+	PriceOriginLista = "LISTA"
+	PriceOriginItem  = "ITEM"
+)
+
+var ErrInvalidPriceListItem = errors.New("item inválido para a lista de preços")
+
+// PriceList é uma tabela de preços do estabelecimento, como varejo ou
+// atacado. AjustePercentual vale para os produtos sem item na lista.
+type PriceList struct {
+	ID                int64           `json:"id"`
+	EstabelecimentoID int64           `json:"estabelecimento_id" binding:"required"`
+	Nome              string          `json:"nome" binding:"required,max=60"`
+	Descricao         string          `json:"descricao"`
+	AjustePercentual  float64         `json:"ajuste_percentual" binding:"gte=-100"`
+	Itens             []PriceListItem `json:"itens,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+}
+
+type PriceListFilter struct {
+	Nome string
+}
+
+// PriceListItem define o preço de um produto na lista a partir de
+// QuantidadeMinima, por valor fixo ou por percentual sobre o valor base. Os
+// valores são por unidade de estoque, como products.valor.
+type PriceListItem struct {
+	ID               int64     `json:"id"`
+	PriceListID      int64     `json:"price_list_id"`
+	ProductID        int64     `json:"product_id" binding:"required"`
+	ProductNome      string    `json:"product_nome"`
+	SKU              string    `json:"sku"`
+	QuantidadeMinima float64   `json:"quantidade_minima" binding:"omitempty,gt=0"`
+	Valor            *float64  `json:"valor" binding:"omitempty,gte=0"`
+	AjustePercentual *float64  `json:"ajuste_percentual" binding:"omitempty,gte=-100"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// PriceResolution é o preço de um produto para uma lista e quantidade.
+type PriceResolution struct {
+	ProductID        int64    `json:"product_id"`
+	PriceListID      *int64   `json:"price_list_id"`
+	Lista            string   `json:"lista,omitempty"`
+	Quantidade       float64  `json:"quantidade"`
+	ValorBase        float64  `json:"valor_base"`
+	ValorUnitario    float64  `json:"valor_unitario"`
+	Total            float64  `json:"total"`
+	Origem           string   `json:"origem"`
+	QuantidadeMinima *float64 `json:"quantidade_minima,omitempty"`
+	AjustePercentual *float64 `json:"ajuste_percentual,omitempty"`
+}
+
+const priceListColumns = "id, estabelecimento_id, nome, descricao, ajuste_percentual, created_at, updated_at"
+
+var PriceListSortColumns = map[string]string{
+	"id":         "id",
+	"nome":       "nome",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+func scanPriceList(row rowScanner, l *PriceList) error {
+	return row.Scan(&l.ID, &l.EstabelecimentoID, &l.Nome, &l.Descricao, &l.AjustePercentual, &l.CreatedAt, &l.UpdatedAt)
+}
+
+func applyPercentage(valor, percentual float64) float64 {
+	return roundMoney(valor * (1 + percentual/100))
+}
+
+func (l *PriceList) Save(tx *sql.Tx, role, userId string) error {
+	err := CheckEstablishmentAccess(role, userId, l.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO price_lists(estabelecimento_id, nome, descricao, ajuste_percentual)
+	VALUES($1, $2, $3, $4)
+	RETURNING id, created_at, updated_at`
+
+	return tx.QueryRow(query, l.EstabelecimentoID, l.Nome, l.Descricao, l.AjustePercentual).Scan(&l.ID, &l.CreatedAt, &l.UpdatedAt)
+}
+
+func GetAllPriceLists(role, userId string, filter PriceListFilter, params ListParams) ([]PriceList, ListResult, error) {
+	baseQuery := "SELECT " + priceListColumns + " FROM price_lists WHERE 1=1"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	if filter.Nome != "" {
+		args = append(args, "%"+filter.Nome+"%")
+		baseQuery += fmt.Sprintf(" AND nome ILIKE $%d", len(args))
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var lists []PriceList
+
+	for rows.Next() {
+		var list PriceList
+		err := scanPriceList(rows, &list)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		lists = append(lists, list)
+	}
+
+	fetched := len(lists)
+	if fetched > params.PerPage {
+		lists = lists[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(lists) > 0 {
+		lastID = lists[len(lists)-1].ID
+	}
+
+	return lists, params.result(total, fetched, lastID), nil
+}
+
+func GetPriceList(id int64, role, userId string) (*PriceList, error) {
+	row := db.DB.QueryRow("SELECT "+priceListColumns+" FROM price_lists WHERE id = $1", id)
+
+	var list PriceList
+
+	err := scanPriceList(row, &list)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, list.EstabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// LoadItems carrega os itens da lista, por produto e faixa de quantidade.
+func (l *PriceList) LoadItems() error {
+	rows, err := db.DB.Query(`SELECT i.id, i.price_list_id, i.product_id, p.nome, p.sku, i.quantidade_minima, i.valor, i.ajuste_percentual, i.created_at, i.updated_at
+	FROM price_list_items i
+	JOIN products p ON p.id = i.product_id
+	WHERE i.price_list_id = $1
+	ORDER BY p.nome, i.product_id, i.quantidade_minima`, l.ID)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	l.Itens = []PriceListItem{}
+
+	for rows.Next() {
+		var item PriceListItem
+		err := rows.Scan(&item.ID, &item.PriceListID, &item.ProductID, &item.ProductNome, &item.SKU, &item.QuantidadeMinima, &item.Valor, &item.AjustePercentual, &item.CreatedAt, &item.UpdatedAt)
+
+		if err != nil {
+			return err
+		}
+
+		l.Itens = append(l.Itens, item)
+	}
+
+	return nil
+}
+
+// Update altera nome, descrição e ajuste da lista. O estabelecimento não muda,
+// já que os itens apontam para produtos dele.
+func (l *PriceList) Update(tx *sql.Tx) error {
+	query := `UPDATE price_lists
+	SET nome = $1, descricao = $2, ajuste_percentual = $3, updated_at = $4
+	WHERE id = $5`
+
+	_, err := tx.Exec(query, l.Nome, l.Descricao, l.AjustePercentual, l.UpdatedAt, l.ID)
+
+	return err
+}
+
+// Delete remove a lista junto com seus itens.
+func (l *PriceList) Delete(tx *sql.Tx) error {
+	_, err := tx.Exec("DELETE FROM price_lists WHERE id = $1", l.ID)
+	return err
+}
+
+// Save cria ou substitui o item do produto na faixa de quantidade informada.
+// O item precisa de valor ou de ajuste percentual, não de ambos, e o produto
+// deve ser do estabelecimento da lista.
+func (i *PriceListItem) Save(tx *sql.Tx, list *PriceList) error {
+	if (i.Valor == nil) == (i.AjustePercentual == nil) {
+		return ErrInvalidPriceListItem
+	}
+
+	if i.QuantidadeMinima == 0 {
+		i.QuantidadeMinima = 1
+	}
+	i.QuantidadeMinima = roundQuantity(i.QuantidadeMinima)
+
+	if i.Valor != nil {
+		valor := roundMoney(*i.Valor)
+		i.Valor = &valor
+	}
+
+	err := tx.QueryRow(
+		"SELECT nome, sku FROM products WHERE id = $1 AND estabelecimento_id = $2 AND deleted_at IS NULL",
+		i.ProductID, list.EstabelecimentoID,
+	).Scan(&i.ProductNome, &i.SKU)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidPriceListItem
+	}
+	if err != nil {
+		return err
+	}
+
+	i.PriceListID = list.ID
+
+	query := `INSERT INTO price_list_items(price_list_id, product_id, quantidade_minima, valor, ajuste_percentual)
+	VALUES($1, $2, $3, $4, $5)
+	ON CONFLICT (price_list_id, product_id, quantidade_minima)
+	DO UPDATE SET valor = EXCLUDED.valor, ajuste_percentual = EXCLUDED.ajuste_percentual, updated_at = NOW()
+	RETURNING id, created_at, updated_at`
+
+	return tx.QueryRow(query, i.PriceListID, i.ProductID, i.QuantidadeMinima, i.Valor, i.AjustePercentual).Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
+}
+
+func DeletePriceListItem(tx *sql.Tx, listId, itemId int64) error {
+	result, err := tx.Exec("DELETE FROM price_list_items WHERE id = $1 AND price_list_id = $2", itemId, listId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// findPriceList busca a lista do estabelecimento pelo nome e, não havendo
+// lista com esse nome, pelo id, para que uma lista chamada "2024" não seja
+// confundida com a lista de id 2024.
+func findPriceList(estabelecimentoId int64, lista string) (*PriceList, error) {
+	var list PriceList
+	row := db.DB.QueryRow("SELECT "+priceListColumns+" FROM price_lists WHERE LOWER(nome) = LOWER($1) AND estabelecimento_id = $2", lista, estabelecimentoId)
+
+	err := scanPriceList(row, &list)
+	if errors.Is(err, sql.ErrNoRows) {
+		listId, parseErr := strconv.ParseInt(lista, 10, 64)
+		if parseErr != nil {
+			return nil, err
+		}
+
+		row = db.DB.QueryRow("SELECT "+priceListColumns+" FROM price_lists WHERE id = $1 AND estabelecimento_id = $2", listId, estabelecimentoId)
+		err = scanPriceList(row, &list)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// ResolveProductPrice calcula o preço unitário do produto para a lista (nome
+// ou id, no estabelecimento do produto) e a quantidade, em unidade de estoque.
+// Vale o item do produto com a maior quantidade mínima atendida; sem item, ou
+// abaixo da primeira faixa do produto, o ajuste da lista; sem lista, o valor
+// base.
+func ResolveProductPrice(product *Product, lista string, quantidade float64) (*PriceResolution, error) {
+	resolution := &PriceResolution{
+		ProductID:     product.ID,
+		Quantidade:    roundQuantity(quantidade),
+		ValorBase:     product.Valor,
+		ValorUnitario: product.Valor,
+		Origem:        PriceOriginBase,
+	}
+
+	if lista != "" {
+		list, err := findPriceList(product.EstabelecimentoID, lista)
+		if err != nil {
+			return nil, err
+		}
+
+		resolution.PriceListID = &list.ID
+		resolution.Lista = list.Nome
+
+		var quantidadeMinima float64
+		var valor, percentual *float64
+		err = db.DB.QueryRow(
+			`SELECT quantidade_minima, valor, ajuste_percentual FROM price_list_items
+			WHERE price_list_id = $1 AND product_id = $2 AND quantidade_minima <= $3
+			ORDER BY quantidade_minima DESC
+			LIMIT 1`,
+			list.ID, product.ID, resolution.Quantidade,
+		).Scan(&quantidadeMinima, &valor, &percentual)
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			resolution.Origem = PriceOriginLista
+			resolution.AjustePercentual = &list.AjustePercentual
+			resolution.ValorUnitario = applyPercentage(product.Valor, list.AjustePercentual)
+		case err != nil:
+			return nil, err
+		default:
+			resolution.Origem = PriceOriginItem
+			resolution.QuantidadeMinima = &quantidadeMinima
+			resolution.AjustePercentual = percentual
+			if valor != nil {
+				resolution.ValorUnitario = *valor
+			} else {
+				resolution.ValorUnitario = applyPercentage(product.Valor, *percentual)
+			}
+		}
+	}
+
+	resolution.Total = roundMoney(resolution.ValorUnitario * resolution.Quantidade)
+
+	return resolution, nil
+}
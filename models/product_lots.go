@@ -0,0 +1,205 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+var (
+	ErrInvalidExpiryDate    = errors.New("data de validade inválida, utilize o formato DD/MM/AAAA")
+	ErrInsufficientLotStock = errors.New("quantidade insuficiente no lote informado")
+)
+
+type ProductLot struct {
+	ID                int64      `json:"id"`
+	ProductID         int64      `json:"product_id"`
+	ProductNome       string     `json:"product_nome,omitempty"`
+	SKU               string     `json:"sku,omitempty"`
+	NumeroLote        string     `json:"numero_lote"`
+	Validade          *time.Time `json:"validade"`
+	QuantidadeInicial float64    `json:"quantidade_inicial"`
+	Quantidade        float64    `json:"quantidade"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type LotConsumption struct {
+	LotID      int64   `json:"lot_id"`
+	NumeroLote string  `json:"numero_lote"`
+	Quantidade float64 `json:"quantidade"`
+}
+
+func parseExpiryDate(validade string) (*time.Time, error) {
+	if validade == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("02/01/2006", validade)
+	if err != nil {
+		return nil, ErrInvalidExpiryDate
+	}
+
+	return &date, nil
+}
+
+// addToLot dá entrada da quantidade no lote informado, criando-o caso ainda
+// não exista para o produto.
+func addToLot(tx *sql.Tx, productId int64, numeroLote, validade string, quantidade float64) (LotConsumption, error) {
+	expiry, err := parseExpiryDate(validade)
+	if err != nil {
+		return LotConsumption{}, err
+	}
+
+	query := `INSERT INTO product_lots(product_id, numero_lote, validade, quantidade_inicial, quantidade)
+	VALUES($1, $2, $3, $4, $4)
+	ON CONFLICT (product_id, numero_lote) DO UPDATE
+	SET quantidade = product_lots.quantidade + EXCLUDED.quantidade,
+		quantidade_inicial = product_lots.quantidade_inicial + EXCLUDED.quantidade_inicial,
+		validade = COALESCE(EXCLUDED.validade, product_lots.validade),
+		updated_at = NOW()
+	RETURNING id`
+
+	consumption := LotConsumption{NumeroLote: numeroLote, Quantidade: quantidade}
+	err = tx.QueryRow(query, productId, numeroLote, expiry, quantidade).Scan(&consumption.LotID)
+
+	return consumption, err
+}
+
+// consumeLots baixa a quantidade dos lotes do produto seguindo FEFO (primeiro
+// que vence, primeiro que sai). Quando um lote é informado, apenas ele é usado.
+// O que exceder o saldo dos lotes é tratado como estoque sem lote.
+func consumeLots(tx *sql.Tx, productId int64, numeroLote string, quantidade float64) ([]LotConsumption, error) {
+	query := `SELECT id, numero_lote, quantidade FROM product_lots
+	WHERE product_id = $1 AND quantidade > 0`
+	args := []interface{}{productId}
+
+	if numeroLote != "" {
+		query += " AND numero_lote = $2"
+		args = append(args, numeroLote)
+	}
+
+	query += " ORDER BY validade ASC NULLS LAST, id FOR UPDATE"
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var lots []LotConsumption
+	for rows.Next() {
+		var lot LotConsumption
+		err := rows.Scan(&lot.LotID, &lot.NumeroLote, &lot.Quantidade)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+	rows.Close()
+
+	restante := quantidade
+	var consumed []LotConsumption
+
+	for _, lot := range lots {
+		if restante <= 0 {
+			break
+		}
+
+		baixa := lot.Quantidade
+		if baixa > restante {
+			baixa = restante
+		}
+
+		_, err := tx.Exec("UPDATE product_lots SET quantidade = quantidade - $1, updated_at = NOW() WHERE id = $2", baixa, lot.LotID)
+		if err != nil {
+			return nil, err
+		}
+
+		consumed = append(consumed, LotConsumption{LotID: lot.LotID, NumeroLote: lot.NumeroLote, Quantidade: baixa})
+		restante = roundQuantity(restante - baixa)
+	}
+
+	if numeroLote != "" && restante > 0 {
+		return nil, ErrInsufficientLotStock
+	}
+
+	return consumed, nil
+}
+
+func GetProductLots(productId int64) ([]ProductLot, error) {
+	query := `SELECT id, product_id, numero_lote, validade, quantidade_inicial, quantidade, created_at, updated_at
+	FROM product_lots
+	WHERE product_id = $1
+	ORDER BY validade ASC NULLS LAST, id`
+
+	rows, err := db.DB.Query(query, productId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var lots []ProductLot
+
+	for rows.Next() {
+		var lot ProductLot
+		err := rows.Scan(&lot.ID, &lot.ProductID, &lot.NumeroLote, &lot.Validade, &lot.QuantidadeInicial, &lot.Quantidade, &lot.CreatedAt, &lot.UpdatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		lots = append(lots, lot)
+	}
+
+	return lots, nil
+}
+
+// GetExpiringLots lista os lotes com saldo que vencem nos próximos dias
+// informados, incluindo os já vencidos.
+func GetExpiringLots(role, userId string, dias int) ([]ProductLot, error) {
+	query := `SELECT l.id, l.product_id, p.nome, p.sku, l.numero_lote, l.validade, l.quantidade_inicial, l.quantidade, l.created_at, l.updated_at
+	FROM product_lots l
+	JOIN products p ON p.id = l.product_id
+	WHERE p.deleted_at IS NULL AND l.quantidade > 0 AND l.validade IS NOT NULL AND l.validade <= CURRENT_DATE + $1::integer`
+	args := []interface{}{dias}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, err
+		}
+
+		query += " AND p.estabelecimento_id = $2"
+		args = append(args, estabelecimentoId)
+	}
+
+	query += " ORDER BY l.validade, l.id"
+
+	rows, err := db.DB.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var lots []ProductLot
+
+	for rows.Next() {
+		var lot ProductLot
+		err := rows.Scan(&lot.ID, &lot.ProductID, &lot.ProductNome, &lot.SKU, &lot.NumeroLote, &lot.Validade, &lot.QuantidadeInicial, &lot.Quantidade, &lot.CreatedAt, &lot.UpdatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		lots = append(lots, lot)
+	}
+
+	return lots, nil
+}
@@ -0,0 +1,312 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+var (
+	ErrInvalidPriceDate    = errors.New("a data de vigência deve ser futura e no formato DD/MM/AAAA")
+	ErrPriceAlreadyApplied = errors.New("a mudança de preço já foi aplicada")
+)
+
+// ProductPrice é um período de vigência do preço de venda. O preço atual tem
+// vigente_ate vazio; mudanças agendadas ainda não têm aplicado_em.
+type ProductPrice struct {
+	ID         int64      `json:"id"`
+	ProductID  int64      `json:"product_id"`
+	Valor      float64    `json:"valor"`
+	VigenteDe  time.Time  `json:"vigente_de"`
+	VigenteAte *time.Time `json:"vigente_ate"`
+	AplicadoEm *time.Time `json:"aplicado_em"`
+	Motivo     string     `json:"motivo"`
+	UserID     int64      `json:"user_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// PriceSchedule é o pedido de mudança de preço a partir de uma data futura,
+// informada como DD/MM/AAAA ou DD/MM/AAAA HH:MM.
+type PriceSchedule struct {
+	Valor     float64 `json:"valor" binding:"required,gt=0"`
+	VigenteDe string  `json:"vigente_de" binding:"required"`
+	Motivo    string  `json:"motivo"`
+}
+
+const productPriceColumns = "id, product_id, valor, vigente_de, vigente_ate, aplicado_em, motivo, COALESCE(user_id, 0), created_at"
+
+func scanProductPrice(row rowScanner, p *ProductPrice) error {
+	return row.Scan(&p.ID, &p.ProductID, &p.Valor, &p.VigenteDe, &p.VigenteAte, &p.AplicadoEm, &p.Motivo, &p.UserID, &p.CreatedAt)
+}
+
+func parsePriceDate(value string) (time.Time, error) {
+	for _, layout := range []string{"02/01/2006 15:04", "02/01/2006"} {
+		date, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, ErrInvalidPriceDate
+}
+
+// recordPriceChange encerra o período de preço aberto e abre um novo, já
+// aplicado, a partir do momento informado.
+func recordPriceChange(tx *sql.Tx, productId int64, valor float64, motivo string, userId int64, vigenteDe time.Time) error {
+	_, err := tx.Exec(
+		"UPDATE product_prices SET vigente_ate = GREATEST(vigente_de, $1) WHERE product_id = $2 AND aplicado_em IS NOT NULL AND vigente_ate IS NULL",
+		vigenteDe, productId,
+	)
+	if err != nil {
+		return err
+	}
+
+	var user interface{}
+	if userId != 0 {
+		user = userId
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO product_prices(product_id, valor, vigente_de, aplicado_em, motivo, user_id)
+		VALUES($1, $2, $3, $3, $4, $5)`,
+		productId, valor, vigenteDe, motivo, user,
+	)
+
+	return err
+}
+
+// SchedulePrice agenda uma mudança de preço. Ela só altera products.valor
+// quando o agendador a aplica.
+func SchedulePrice(tx *sql.Tx, productId int64, schedule PriceSchedule, userId int64) (*ProductPrice, error) {
+	vigenteDe, err := parsePriceDate(schedule.VigenteDe)
+	if err != nil {
+		return nil, err
+	}
+
+	if !vigenteDe.After(time.Now()) {
+		return nil, ErrInvalidPriceDate
+	}
+
+	var user interface{}
+	if userId != 0 {
+		user = userId
+	}
+
+	row := tx.QueryRow(
+		`INSERT INTO product_prices(product_id, valor, vigente_de, motivo, user_id)
+		VALUES($1, $2, $3, $4, $5)
+		RETURNING `+productPriceColumns,
+		productId, roundMoney(schedule.Valor), vigenteDe, schedule.Motivo, user,
+	)
+
+	var price ProductPrice
+	err = scanProductPrice(row, &price)
+	if err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
+
+// CancelScheduledPrice remove uma mudança de preço que ainda não foi
+// aplicada.
+func CancelScheduledPrice(tx *sql.Tx, productId, priceId int64) error {
+	var aplicadoEm *time.Time
+	err := tx.QueryRow(
+		"SELECT aplicado_em FROM product_prices WHERE id = $1 AND product_id = $2 FOR UPDATE",
+		priceId, productId,
+	).Scan(&aplicadoEm)
+	if err != nil {
+		return err
+	}
+
+	if aplicadoEm != nil {
+		return ErrPriceAlreadyApplied
+	}
+
+	_, err = tx.Exec("DELETE FROM product_prices WHERE id = $1", priceId)
+
+	return err
+}
+
+// GetProductPrices lista os períodos de preço do produto, dos agendados aos
+// mais antigos.
+func GetProductPrices(productId int64) ([]ProductPrice, error) {
+	query := "SELECT " + productPriceColumns + `
+	FROM product_prices
+	WHERE product_id = $1
+	ORDER BY vigente_de DESC, id DESC`
+
+	rows, err := db.DB.Query(query, productId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var prices []ProductPrice
+
+	for rows.Next() {
+		var price ProductPrice
+		err := scanProductPrice(rows, &price)
+
+		if err != nil {
+			return nil, err
+		}
+
+		prices = append(prices, price)
+	}
+
+	return prices, nil
+}
+
+// GetProductPriceAt retorna o preço que estava em vigor ao fim do dia
+// informado.
+func GetProductPriceAt(productId int64, data time.Time) (*ProductPrice, error) {
+	query := "SELECT " + productPriceColumns + `
+	FROM product_prices
+	WHERE product_id = $1 AND aplicado_em IS NOT NULL AND vigente_de < $2 AND (vigente_ate IS NULL OR vigente_ate >= $2)
+	ORDER BY vigente_de DESC, id DESC
+	LIMIT 1`
+
+	var price ProductPrice
+	err := scanProductPrice(db.DB.QueryRow(query, productId, data.AddDate(0, 0, 1)), &price)
+	if err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
+
+// applyScheduledPrice aplica uma mudança agendada com o produto bloqueado. Se
+// ela foi cancelada ou aplicada por outra execução, nada acontece. Se o preço
+// atual começou a valer depois da data agendada, a alteração posterior
+// prevalece: a mudança agendada entra no histórico como um período vazio, que
+// nunca esteve em vigor, e o preço do produto não muda. A troca de preço é
+// registrada na auditoria em nome do sistema.
+func applyScheduledPrice(priceId, productId int64) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var valorAnterior float64
+	var estabelecimentoId int64
+	err = tx.QueryRow("SELECT valor, estabelecimento_id FROM products WHERE id = $1 FOR UPDATE", productId).Scan(&valorAnterior, &estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	var valor float64
+	var vigenteDe time.Time
+	err = tx.QueryRow(
+		"SELECT valor, vigente_de FROM product_prices WHERE id = $1 AND aplicado_em IS NULL FOR UPDATE",
+		priceId,
+	).Scan(&valor, &vigenteDe)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var atualDe time.Time
+	err = tx.QueryRow(
+		"SELECT vigente_de FROM product_prices WHERE product_id = $1 AND aplicado_em IS NOT NULL AND vigente_ate IS NULL",
+		productId,
+	).Scan(&atualDe)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err == nil && !atualDe.Before(vigenteDe) {
+		_, err = tx.Exec("UPDATE product_prices SET aplicado_em = NOW(), vigente_ate = vigente_de WHERE id = $1", priceId)
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	_, err = tx.Exec(
+		"UPDATE product_prices SET vigente_ate = $1 WHERE product_id = $2 AND aplicado_em IS NOT NULL AND vigente_ate IS NULL",
+		vigenteDe, productId,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE product_prices SET aplicado_em = NOW() WHERE id = $1", priceId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE products SET valor = $1, updated_at = NOW() WHERE id = $2", valor, productId)
+	if err != nil {
+		return err
+	}
+
+	err = RecordAudit(
+		tx, SystemActor, AuditProduct, productId, estabelecimentoId, AuditUpdate,
+		map[string]interface{}{"valor": valorAnterior}, map[string]interface{}{"valor": valor},
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ApplyScheduledPrices aplica, em ordem de vigência, as mudanças de preço
+// agendadas que já começaram a valer. Cada uma tem sua própria transação; uma
+// mudança que falha é registrada no log e não impede as seguintes.
+func ApplyScheduledPrices() error {
+	rows, err := db.DB.Query(`SELECT id, product_id FROM product_prices
+	WHERE aplicado_em IS NULL AND vigente_de <= NOW()
+	ORDER BY vigente_de, id`)
+	if err != nil {
+		return err
+	}
+
+	type due struct{ id, productId int64 }
+	var pending []due
+
+	for rows.Next() {
+		var d due
+		err := rows.Scan(&d.id, &d.productId)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		pending = append(pending, d)
+	}
+	rows.Close()
+
+	for _, d := range pending {
+		err = applyScheduledPrice(d.id, d.productId)
+		if err != nil {
+			log.Printf("Erro ao aplicar o preço agendado #%d: %v", d.id, err)
+		}
+	}
+
+	return nil
+}
+
+// StartPriceScheduler executa ApplyScheduledPrices periodicamente. Deve ser
+// chamado em uma goroutine própria.
+func StartPriceScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := ApplyScheduledPrices()
+		if err != nil {
+			log.Println("Erro ao aplicar preços agendados:", err)
+		}
+	}
+}
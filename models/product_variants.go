@@ -0,0 +1,353 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInvalidVariant           = errors.New("o produto pai deve existir no mesmo estabelecimento e não pode ser uma variante")
+	ErrInvalidVariantAttributes = errors.New("os atributos da variante não correspondem às definições do produto pai")
+	ErrDuplicateVariant         = errors.New("já existe uma variante com essa combinação de atributos")
+	ErrParentProductStock       = errors.New("o estoque de produtos com variantes é controlado nas variantes")
+	ErrAttributeInUse           = errors.New("as definições de atributos não atendem às variantes existentes")
+)
+
+// VariantAttributes guarda os valores de atributo de uma variante, como
+// {"Tamanho": "M", "Cor": "Azul"}, na coluna JSONB products.atributos.
+type VariantAttributes map[string]string
+
+func (a VariantAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+
+	raw, err := json.Marshal(a)
+	return string(raw), err
+}
+
+func (a *VariantAttributes) Scan(src interface{}) error {
+	var raw []byte
+	switch value := src.(type) {
+	case []byte:
+		raw = value
+	case string:
+		raw = []byte(value)
+	case nil:
+		*a = nil
+		return nil
+	default:
+		return fmt.Errorf("tipo inválido para atributos: %T", src)
+	}
+
+	attributes := VariantAttributes{}
+	err := json.Unmarshal(raw, &attributes)
+	if err != nil {
+		return err
+	}
+
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+	*a = attributes
+
+	return nil
+}
+
+// ProductAttribute define um atributo das variantes de um produto pai. Sem
+// valores, qualquer valor é aceito.
+type ProductAttribute struct {
+	ID        int64    `json:"id"`
+	ProductID int64    `json:"product_id"`
+	Nome      string   `json:"nome" binding:"required"`
+	Valores   []string `json:"valores"`
+}
+
+func getProductAttributes(q queryer, productId int64) ([]ProductAttribute, error) {
+	rows, err := q.Query("SELECT id, product_id, nome, valores FROM product_attributes WHERE product_id = $1 ORDER BY posicao, id", productId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	attributes := []ProductAttribute{}
+
+	for rows.Next() {
+		var attribute ProductAttribute
+		err := rows.Scan(&attribute.ID, &attribute.ProductID, &attribute.Nome, pq.Array(&attribute.Valores))
+
+		if err != nil {
+			return nil, err
+		}
+
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes, nil
+}
+
+func GetProductAttributes(productId int64) ([]ProductAttribute, error) {
+	return getProductAttributes(db.DB, productId)
+}
+
+// normalizeVariantAttributes confere se a variante informa exatamente os
+// atributos definidos, com valores permitidos, e devolve nomes e valores com a
+// grafia das definições.
+func normalizeVariantAttributes(definitions []ProductAttribute, values VariantAttributes) (VariantAttributes, error) {
+	if len(definitions) == 0 || len(values) != len(definitions) {
+		return nil, ErrInvalidVariantAttributes
+	}
+
+	normalized := VariantAttributes{}
+
+	for _, definition := range definitions {
+		var value string
+		found := false
+		for nome, v := range values {
+			if strings.EqualFold(strings.TrimSpace(nome), definition.Nome) {
+				value = strings.TrimSpace(v)
+				found = true
+				break
+			}
+		}
+
+		if !found || value == "" {
+			return nil, ErrInvalidVariantAttributes
+		}
+
+		if len(definition.Valores) > 0 {
+			allowed := false
+			for _, option := range definition.Valores {
+				if strings.EqualFold(option, value) {
+					value = option
+					allowed = true
+					break
+				}
+			}
+
+			if !allowed {
+				return nil, ErrInvalidVariantAttributes
+			}
+		}
+
+		normalized[definition.Nome] = value
+	}
+
+	return normalized, nil
+}
+
+// validateVariant confere o produto pai e os atributos de uma variante. O pai
+// é bloqueado para que variantes concorrentes não repitam a mesma combinação.
+func validateVariant(tx *sql.Tx, p *Product) error {
+	var estabelecimentoId int64
+	var parentId *int64
+	var categoriaId *int64
+	var estoque float64
+
+	err := tx.QueryRow(
+		"SELECT estabelecimento_id, parent_id, categoria_id, estoque FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+		*p.ParentID,
+	).Scan(&estabelecimentoId, &parentId, &categoriaId, &estoque)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidVariant
+	}
+	if err != nil {
+		return err
+	}
+
+	if parentId != nil || estabelecimentoId != p.EstabelecimentoID || *p.ParentID == p.ID {
+		return ErrInvalidVariant
+	}
+
+	if estoque != 0 {
+		return ErrParentProductStock
+	}
+
+	if p.CategoriaID == nil {
+		p.CategoriaID = categoriaId
+	}
+
+	definitions, err := getProductAttributes(tx, *p.ParentID)
+	if err != nil {
+		return err
+	}
+
+	p.Atributos, err = normalizeVariantAttributes(definitions, p.Atributos)
+	if err != nil {
+		return err
+	}
+
+	var duplicate bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM products WHERE parent_id = $1 AND atributos = $2::jsonb AND id <> $3 AND deleted_at IS NULL)",
+		*p.ParentID, p.Atributos, p.ID,
+	).Scan(&duplicate)
+	if err != nil {
+		return err
+	}
+
+	if duplicate {
+		return ErrDuplicateVariant
+	}
+
+	return nil
+}
+
+// SetProductAttributes substitui as definições de atributos de um produto pai.
+// As variantes já cadastradas precisam continuar válidas com as novas
+// definições.
+func SetProductAttributes(tx *sql.Tx, productId int64, attributes []ProductAttribute) error {
+	var parentId *int64
+	err := tx.QueryRow("SELECT parent_id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", productId).Scan(&parentId)
+	if err != nil {
+		return err
+	}
+
+	if parentId != nil {
+		return ErrInvalidVariant
+	}
+
+	seen := map[string]bool{}
+	for i := range attributes {
+		attribute := &attributes[i]
+		attribute.Nome = strings.TrimSpace(attribute.Nome)
+
+		key := strings.ToLower(attribute.Nome)
+		if attribute.Nome == "" || seen[key] {
+			return ErrInvalidVariantAttributes
+		}
+		seen[key] = true
+
+		valores := []string{}
+		for _, valor := range attribute.Valores {
+			valor = strings.TrimSpace(valor)
+			if valor != "" {
+				valores = append(valores, valor)
+			}
+		}
+		attribute.Valores = valores
+	}
+
+	_, err = tx.Exec("DELETE FROM product_attributes WHERE product_id = $1", productId)
+	if err != nil {
+		return err
+	}
+
+	for i := range attributes {
+		attribute := &attributes[i]
+		attribute.ProductID = productId
+
+		err := tx.QueryRow(
+			"INSERT INTO product_attributes(product_id, nome, valores, posicao) VALUES($1, $2, $3, $4) RETURNING id",
+			productId, attribute.Nome, pq.Array(attribute.Valores), i,
+		).Scan(&attribute.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	variants, err := getProductVariants(tx, []int64{productId}, false)
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		normalized, err := normalizeVariantAttributes(attributes, variant.Atributos)
+		if err != nil {
+			return ErrAttributeInUse
+		}
+
+		_, err = tx.Exec("UPDATE products SET atributos = $1 WHERE id = $2", normalized, variant.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getProductVariants(q queryer, parentIds []int64, includeDeleted bool) ([]Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE parent_id = ANY($1)"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	query += " ORDER BY id"
+
+	rows, err := q.Query(query, pq.Array(parentIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var variants []Product
+
+	for rows.Next() {
+		var variant Product
+		err := scanProduct(rows, &variant)
+
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, variant)
+	}
+
+	err = fillKitStock(q, variants)
+	if err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// attachVariants preenche as variantes de cada produto da lista.
+func attachVariants(products []Product, includeDeleted bool) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(products))
+	index := map[int64]int{}
+	for i, product := range products {
+		ids = append(ids, product.ID)
+		index[product.ID] = i
+	}
+
+	variants, err := getProductVariants(db.DB, ids, includeDeleted)
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		i := index[*variant.ParentID]
+		products[i].Variantes = append(products[i].Variantes, variant)
+	}
+
+	return nil
+}
+
+// LoadVariants carrega as variantes ativas do produto.
+func (p *Product) LoadVariants() error {
+	products := []Product{*p}
+
+	err := attachVariants(products, false)
+	if err != nil {
+		return err
+	}
+
+	p.Variantes = products[0].Variantes
+
+	return nil
+}
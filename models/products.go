@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"strconv"
@@ -11,51 +12,175 @@ import (
 )
 
 type Product struct {
-	ID                int64     `json:"id"`
-	Nome              string    `json:"nome" binding:"required"`
-	Descricao         string    `json:"descricao" binding:"required"`
-	Valor             float64   `json:"valor" binding:"required"`
-	Estoque           float64   `json:"estoque" binding:"required"`
-	EstabelecimentoID int64     `json:"estabelecimento_id" binding:"required"`
-	SKU               string    `json:"sku" binding:"required"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                int64             `json:"id"`
+	Nome              string            `json:"nome" binding:"required"`
+	Descricao         string            `json:"descricao" binding:"required"`
+	Valor             float64           `json:"valor" binding:"required"`
+	Estoque           float64           `json:"estoque" binding:"required"`
+	EstabelecimentoID int64             `json:"estabelecimento_id" binding:"required"`
+	SKU               string            `json:"sku" binding:"required"`
+	EstoqueMinimo     float64           `json:"estoque_minimo" binding:"gte=0"`
+	PontoDePedido     float64           `json:"ponto_de_pedido" binding:"gte=0"`
+	CategoriaID       *int64            `json:"categoria_id"`
+	Tipo              string            `json:"tipo" binding:"omitempty,oneof=SIMPLES KIT"`
+	Unidade           string            `json:"unidade"`
+	UnidadeCompra     string            `json:"unidade_compra"`
+	FatorCompra       float64           `json:"fator_compra" binding:"gte=0"`
+	UnidadeVenda      string            `json:"unidade_venda"`
+	FatorVenda        float64           `json:"fator_venda" binding:"gte=0"`
+	CodigoBalanca     *string           `json:"codigo_balanca"`
+	ModoBalanca       *string           `json:"modo_balanca" binding:"omitempty,oneof=PESO PRECO"`
+	CustoInicial      *float64          `json:"custo_inicial,omitempty" binding:"omitempty,gte=0"`
+	EstoqueAvariado   float64           `json:"estoque_avariado"`
+	Reservado         float64           `json:"reservado"`
+	Disponivel        float64           `json:"disponivel"`
+	ParentID          *int64            `json:"parent_id"`
+	Atributos         VariantAttributes `json:"atributos,omitempty"`
+	Variantes         []Product         `json:"variantes,omitempty"`
+	Localizacoes      []ProductLocation `json:"localizacoes,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	DeletedAt         *time.Time        `json:"deleted_at,omitempty"`
 }
 
 type ProductFilter struct {
-	SKU         string
-	Description string
-	Valor       string
-	StartDate   string
-	EndDate     string
+	SKU            string
+	Description    string
+	Valor          string
+	StartDate      string
+	EndDate        string
+	CategoryID     string
+	ParentID       string
+	GroupVariants  bool
+	IncludeDeleted bool
 }
 
-func (p *Product) Save() error {
+const productColumns = "id, nome, sku, descricao, valor, estoque, estoque_minimo, ponto_de_pedido, categoria_id, tipo, unidade, unidade_compra, fator_compra, unidade_venda, fator_venda, codigo_balanca, modo_balanca, estoque_avariado, estoque_reservado, parent_id, atributos, created_at, updated_at, estabelecimento_id, deleted_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProduct lê as colunas de productColumns e calcula o disponível, que é o
+// estoque menos o que está reservado.
+func scanProduct(row rowScanner, p *Product) error {
+	err := row.Scan(&p.ID, &p.Nome, &p.SKU, &p.Descricao, &p.Valor, &p.Estoque, &p.EstoqueMinimo, &p.PontoDePedido, &p.CategoriaID, &p.Tipo, &p.Unidade, &p.UnidadeCompra, &p.FatorCompra, &p.UnidadeVenda, &p.FatorVenda, &p.CodigoBalanca, &p.ModoBalanca, &p.EstoqueAvariado, &p.Reservado, &p.ParentID, &p.Atributos, &p.CreatedAt, &p.UpdatedAt, &p.EstabelecimentoID, &p.DeletedAt)
+	if err != nil {
+		return err
+	}
+
+	p.Disponivel = roundQuantity(p.Estoque - p.Reservado)
+	return nil
+}
+
+// Save cadastra o produto. Kits não recebem estoque inicial: o disponível é
+// calculado a partir dos componentes.
+func (p *Product) Save(tx *sql.Tx, userId int64) error {
+	if p.Estoque < 0 {
+		return ErrInvalidQuantity
+	}
+
+	if p.Tipo == "" {
+		p.Tipo = ProductSimples
+	}
+
+	err := p.normalizeUnits()
+	if err != nil {
+		return err
+	}
+
+	if p.ParentID != nil {
+		err := validateVariant(tx, p)
+		if err != nil {
+			return err
+		}
+	} else {
+		p.Atributos = nil
+	}
+
+	err = checkProductCategory(tx, p)
+	if err != nil {
+		return err
+	}
+
+	err = p.normalizeScaleCode(tx)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO products (nome, sku, descricao, valor, estoque, estabelecimento_id)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO products (nome, sku, descricao, valor, estoque, estoque_minimo, ponto_de_pedido, categoria_id, tipo,
+			unidade, unidade_compra, fator_compra, unidade_venda, fator_venda, codigo_balanca, modo_balanca, parent_id, atributos, estabelecimento_id)
+		VALUES ($1, $2, $3, $4, 0, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id, created_at, updated_at
 	`
 
-	err := db.DB.QueryRow(
+	err = tx.QueryRow(
 		query,
-		p.Nome, p.SKU, p.Descricao, p.Valor, p.Estoque, p.EstabelecimentoID,
+		p.Nome, p.SKU, p.Descricao, p.Valor, p.EstoqueMinimo, p.PontoDePedido, p.CategoriaID, p.Tipo,
+		p.Unidade, p.UnidadeCompra, p.FatorCompra, p.UnidadeVenda, p.FatorVenda, p.CodigoBalanca, p.ModoBalanca, p.ParentID, p.Atributos, p.EstabelecimentoID,
 	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	err = recordPriceChange(tx, p.ID, p.Valor, "Preço inicial", userId, p.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if p.Tipo == ProductKit {
+		p.Estoque = 0
+		return nil
+	}
+
+	if p.Estoque == 0 {
+		return nil
+	}
+
+	movement := StockMovement{
+		ProductID:     p.ID,
+		Tipo:          MovementEntrada,
+		Quantidade:    p.Estoque,
+		Motivo:        "Estoque inicial",
+		UserID:        userId,
+		CustoUnitario: p.CustoInicial,
+	}
+
+	err = movement.Save(tx)
+	if err != nil {
+		return err
+	}
+
+	p.Estoque = movement.EstoquePosterior
+	p.Disponivel = p.Estoque
+	p.UpdatedAt = movement.CreatedAt
+
+	return nil
 }
 
-func GetAllProducts(role, userId string, filter ProductFilter) ([]Product, error) {
-	baseQuery := "SELECT * FROM products WHERE 1=1"
+var ProductSortColumns = map[string]string{
+	"id":         "id",
+	"nome":       "nome",
+	"sku":        "sku",
+	"valor":      "valor",
+	"estoque":    "estoque",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+func GetAllProducts(role, userId string, filter ProductFilter, params ListParams) ([]Product, ListResult, error) {
+	baseQuery := "SELECT " + productColumns + " FROM products WHERE 1=1"
 	args := []interface{}{}
 	argIndex := 1
 
-	if role != "OWNER" {
+	if !IsGlobalOwner(role, userId) {
 		var estabelecimentoId int
 		err := db.DB.QueryRow("SELECT estabelecimento_id FROM users WHERE id = $1", userId).Scan(&estabelecimentoId)
 
 		if err != nil {
-			return nil, err
+			return nil, ListResult{}, err
 		}
 
 		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", argIndex)
@@ -63,11 +188,27 @@ func GetAllProducts(role, userId string, filter ProductFilter) ([]Product, error
 		argIndex++
 	}
 
+	if !filter.IncludeDeleted {
+		baseQuery += " AND deleted_at IS NULL"
+	}
+
 	if filter.SKU != "" {
 		baseQuery += fmt.Sprintf(" AND sku = $%d", argIndex)
 		args = append(args, filter.SKU)
 		argIndex++
 	}
+	if categoryId, err := strconv.ParseInt(filter.CategoryID, 10, 64); err == nil {
+		baseQuery += fmt.Sprintf(" AND categoria_id IN (%s)", categoryTreeQuery(argIndex))
+		args = append(args, categoryId)
+		argIndex++
+	}
+	if parentId, err := strconv.ParseInt(filter.ParentID, 10, 64); err == nil {
+		baseQuery += fmt.Sprintf(" AND parent_id = $%d", argIndex)
+		args = append(args, parentId)
+		argIndex++
+	} else if filter.GroupVariants {
+		baseQuery += " AND parent_id IS NULL"
+	}
 	if filter.Description != "" {
 		baseQuery += fmt.Sprintf(" AND descricao ILIKE $%d", argIndex)
 		args = append(args, "%"+filter.Description+"%")
@@ -95,7 +236,83 @@ func GetAllProducts(role, userId string, filter ProductFilter) ([]Product, error
 		}
 	}
 
-	rows, err := db.DB.Query(baseQuery, args...)
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var products []Product
+
+	for rows.Next() {
+		var product Product
+		err := scanProduct(rows, &product)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		products = append(products, product)
+	}
+
+	fetched := len(products)
+	if fetched > params.PerPage {
+		products = products[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(products) > 0 {
+		lastID = products[len(products)-1].ID
+	}
+
+	err = fillKitStock(db.DB, products)
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	if filter.GroupVariants {
+		err = attachVariants(products, filter.IncludeDeleted)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+	}
+
+	return products, params.result(total, fetched, lastID), nil
+}
+
+// GetLowStockProducts lista os produtos que atingiram o ponto de pedido ou
+// ficaram abaixo do estoque mínimo, com o mesmo escopo de GetAllProducts.
+func GetLowStockProducts(role, userId string) ([]Product, error) {
+	query := "SELECT " + productColumns + ` FROM products
+	WHERE deleted_at IS NULL AND tipo <> 'KIT'
+	AND ((ponto_de_pedido > 0 AND estoque <= ponto_de_pedido) OR (estoque_minimo > 0 AND estoque < estoque_minimo))`
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		query += " AND estabelecimento_id = $1"
+		args = append(args, estabelecimentoId)
+	}
+
+	query += " ORDER BY estoque - ponto_de_pedido, id"
+
+	rows, err := db.DB.Query(query, args...)
 
 	if err != nil {
 		return nil, err
@@ -107,7 +324,7 @@ func GetAllProducts(role, userId string, filter ProductFilter) ([]Product, error
 
 	for rows.Next() {
 		var product Product
-		err := rows.Scan(&product.ID, &product.Nome, &product.SKU, &product.Descricao, &product.Valor, &product.Estoque, &product.CreatedAt, &product.UpdatedAt, &product.EstabelecimentoID)
+		err := scanProduct(rows, &product)
 
 		if err != nil {
 			return nil, err
@@ -120,18 +337,31 @@ func GetAllProducts(role, userId string, filter ProductFilter) ([]Product, error
 }
 
 func GetProduct(id int64, role, userId string) (*Product, error) {
-	query := "SELECT * FROM products WHERE id = $1"
+	return findProduct(id, role, userId, false)
+}
+
+// GetProductWithDeleted também encontra produtos excluídos, para restauração
+// e expurgo.
+func GetProductWithDeleted(id int64, role, userId string) (*Product, error) {
+	return findProduct(id, role, userId, true)
+}
+
+func findProduct(id int64, role, userId string, includeDeleted bool) (*Product, error) {
+	query := "SELECT " + productColumns + " FROM products WHERE id = $1"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 	row := db.DB.QueryRow(query, id)
 
 	var product Product
 
-	err := row.Scan(&product.ID, &product.Nome, &product.SKU, &product.Descricao, &product.Valor, &product.Estoque, &product.CreatedAt, &product.UpdatedAt, &product.EstabelecimentoID)
+	err := scanProduct(row, &product)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if role != "OWNER" {
+	if !IsGlobalOwner(role, userId) {
 		var userEstabelecimentoId int64
 		err := db.DB.QueryRow("SELECT estabelecimento_id FROM users WHERE id = $1", userId).Scan(&userEstabelecimentoId)
 		if err != nil {
@@ -143,41 +373,171 @@ func GetProduct(id int64, role, userId string) (*Product, error) {
 		}
 	}
 
+	if product.Tipo == ProductKit {
+		product.Estoque, err = kitAvailable(db.DB, product.ID)
+		if err != nil {
+			return nil, err
+		}
+		product.Disponivel = product.Estoque
+	}
+
 	return &product, nil
 }
 
-func (p *Product) Update(role string) error {
-	if role != "OWNER" {
-		var currentEstabID int64
-		err := db.DB.QueryRow("SELECT estabelecimento_id FROM products WHERE id = $1", p.ID).Scan(&currentEstabID)
+// Update altera os dados cadastrais do produto. Diferenças no estoque não são
+// gravadas diretamente: viram uma movimentação de ajuste no mesmo tx. Uma
+// mudança de valor abre um novo período no histórico de preços. O tipo
+// não muda e o estoque de kits é ignorado, pois vem dos componentes. A unidade
+// de estoque só passa a ser inteira se o saldo atual for inteiro.
+func (p *Product) Update(tx *sql.Tx, role string, userId int64) error {
+	err := lockProducts(tx, []int64{p.ID})
+	if err != nil {
+		return err
+	}
+
+	var currentEstabID int64
+	var currentEstoque float64
+	var current Product
+	err = tx.QueryRow(
+		"SELECT estabelecimento_id, estoque, estoque_reservado, valor, parent_id, tipo, unidade, unidade_compra, fator_compra, unidade_venda, fator_venda FROM products WHERE id = $1 FOR UPDATE",
+		p.ID,
+	).Scan(&currentEstabID, &currentEstoque, &p.Reservado, &current.Valor, &p.ParentID, &p.Tipo, &current.Unidade, &current.UnidadeCompra, &current.FatorCompra, &current.UnidadeVenda, &current.FatorVenda)
+	if err != nil {
+		return err
+	}
+
+	// Sem unidade na requisição, as unidades atuais são mantidas.
+	if p.Unidade == "" {
+		p.Unidade = current.Unidade
+		p.UnidadeCompra = current.UnidadeCompra
+		p.FatorCompra = current.FatorCompra
+		p.UnidadeVenda = current.UnidadeVenda
+		p.FatorVenda = current.FatorVenda
+	}
+
+	err = p.normalizeUnits()
+	if err != nil {
+		return err
+	}
+
+	if checkUnitQuantity(p.Unidade, currentEstoque) != nil {
+		return ErrInvalidUnit
+	}
+
+	if !IsGlobalOwner(role, fmt.Sprintf("%d", userId)) {
+		p.EstabelecimentoID = currentEstabID
+	}
+
+	if p.ParentID != nil {
+		err = validateVariant(tx, p)
+		if err != nil {
+			return err
+		}
+	} else {
+		p.Atributos = nil
+	}
+
+	err = checkProductCategory(tx, p)
+	if err != nil {
+		return err
+	}
+
+	err = p.normalizeScaleCode(tx)
+	if err != nil {
+		return err
+	}
+
+	if p.EstabelecimentoID != currentEstabID {
+		err = moveBarcodes(tx, p.ID, p.EstabelecimentoID)
+		if err != nil {
+			return err
+		}
+
+		err = relocateProduct(tx, p.ID, p.EstabelecimentoID)
 		if err != nil {
 			return err
 		}
-		p.EstabelecimentoID = currentEstabID
 	}
 
 	query := `UPDATE products
-	SET nome = $1, sku = $2, descricao = $3, valor = $4, estoque = $5, updated_at = $6, estabelecimento_id = $7
-	WHERE id = $8`
+	SET nome = $1, sku = $2, descricao = $3, valor = $4, estoque_minimo = $5, ponto_de_pedido = $6, categoria_id = $7, atributos = $8, updated_at = $9, estabelecimento_id = $10,
+	unidade = $11, unidade_compra = $12, fator_compra = $13, unidade_venda = $14, fator_venda = $15, codigo_balanca = $16, modo_balanca = $17
+	WHERE id = $18`
 
-	stmt, err := db.DB.Prepare(query)
+	stmt, err := tx.Prepare(query)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(p.Nome, p.SKU, p.Descricao, p.Valor, p.Estoque, p.UpdatedAt, p.EstabelecimentoID, p.ID)
+	_, err = stmt.Exec(p.Nome, p.SKU, p.Descricao, p.Valor, p.EstoqueMinimo, p.PontoDePedido, p.CategoriaID, p.Atributos, p.UpdatedAt, p.EstabelecimentoID,
+		p.Unidade, p.UnidadeCompra, p.FatorCompra, p.UnidadeVenda, p.FatorVenda, p.CodigoBalanca, p.ModoBalanca, p.ID)
 	if err != nil {
 		return err
 	}
 
+	if roundMoney(p.Valor) != roundMoney(current.Valor) {
+		err = recordPriceChange(tx, p.ID, p.Valor, "Atualização do produto", userId, p.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.Tipo == ProductKit {
+		p.Estoque, err = kitAvailable(tx, p.ID)
+		p.Disponivel = p.Estoque
+		return err
+	}
+
+	diff := roundQuantity(p.Estoque - currentEstoque)
+	if diff == 0 {
+		p.Disponivel = roundQuantity(p.Estoque - p.Reservado)
+		return nil
+	}
+
+	movement := StockMovement{
+		ProductID:  p.ID,
+		Tipo:       MovementAjuste,
+		Quantidade: diff,
+		Motivo:     "Ajuste pela atualização do produto",
+		UserID:     userId,
+	}
+
+	err = movement.Save(tx)
+	if err != nil {
+		return err
+	}
+
+	p.Estoque = movement.EstoquePosterior
+	p.Disponivel = roundQuantity(p.Estoque - p.Reservado)
+
 	return nil
 }
 
-func (p *Product) Delete() error {
+// Delete faz a exclusão lógica do produto, preservando seu histórico. As
+// variantes de um produto pai são excluídas junto.
+func (p *Product) Delete(tx *sql.Tx) error {
+	_, err := tx.Exec("UPDATE products SET deleted_at = NOW() WHERE (id = $1 OR parent_id = $1) AND deleted_at IS NULL", p.ID)
+	return err
+}
+
+// Restore traz de volta o produto e as variantes excluídas junto com ele.
+func (p *Product) Restore(tx *sql.Tx) error {
+	var deletedAt time.Time
+	err := tx.QueryRow("SELECT deleted_at FROM products WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE", p.ID).Scan(&deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE products SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 OR (parent_id = $1 AND deleted_at = $2)", p.ID, deletedAt)
+	return err
+}
+
+// Purge remove o produto definitivamente, junto com movimentações e lotes.
+func (p *Product) Purge(tx *sql.Tx) error {
 	query := "DELETE FROM products WHERE id = $1"
 
-	stmt, err := db.DB.Prepare(query)
+	stmt, err := tx.Prepare(query)
 
 	if err != nil {
 		return err
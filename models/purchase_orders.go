@@ -0,0 +1,613 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	PurchaseOrderRascunho             = "RASCUNHO"
+	PurchaseOrderEnviado              = "ENVIADO"
+	PurchaseOrderParcialmenteRecebido = "PARCIALMENTE_RECEBIDO"
+	PurchaseOrderRecebido             = "RECEBIDO"
+	PurchaseOrderCancelado            = "CANCELADO"
+)
+
+// DefaultPurchaseApprovalLimit é usado quando PURCHASE_ORDER_APPROVAL_LIMIT
+// não está definido no .env.
+const DefaultPurchaseApprovalLimit = 5000.0
+
+var (
+	ErrInvalidPurchaseOrderStatus = errors.New("o pedido de compra não está em um status que permita essa operação")
+	ErrPurchaseApprovalRequired   = errors.New("o pedido de compra excede o limite e precisa da aprovação de um OWNER")
+	ErrInvalidPurchaseOrderItem   = errors.New("item inválido para o pedido de compra")
+	ErrReceiptExceedsOrdered      = errors.New("a quantidade recebida excede a quantidade pendente do item")
+)
+
+// PurchaseOrderItem é pedido e recebido na unidade de compra do produto, com
+// custo por unidade de compra. Fator converte a quantidade para a unidade de
+// estoque.
+type PurchaseOrderItem struct {
+	ID                 int64   `json:"id"`
+	ProductID          int64   `json:"product_id" binding:"required"`
+	ProductNome        string  `json:"product_nome"`
+	Quantidade         float64 `json:"quantidade" binding:"required,gt=0"`
+	QuantidadeRecebida float64 `json:"quantidade_recebida"`
+	Unidade            string  `json:"unidade"`
+	Fator              float64 `json:"fator"`
+	CustoUnitario      float64 `json:"custo_unitario" binding:"gte=0"`
+}
+
+type PurchaseOrder struct {
+	ID                int64               `json:"id"`
+	SupplierID        int64               `json:"supplier_id" binding:"required"`
+	EstabelecimentoID int64               `json:"estabelecimento_id"`
+	Status            string              `json:"status"`
+	Observacao        string              `json:"observacao"`
+	ValorTotal        float64             `json:"valor_total"`
+	CriadoPor         *int64              `json:"criado_por"`
+	AprovadoPor       *int64              `json:"aprovado_por"`
+	AprovadoEm        *time.Time          `json:"aprovado_em"`
+	EnviadoEm         *time.Time          `json:"enviado_em"`
+	RecebidoEm        *time.Time          `json:"recebido_em"`
+	CanceladoEm       *time.Time          `json:"cancelado_em"`
+	Itens             []PurchaseOrderItem `json:"itens" binding:"required,min=1,dive"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+}
+
+type PurchaseOrderFilter struct {
+	Status     string
+	SupplierID string
+}
+
+// PurchaseReceiptItem informa quanto de um produto chegou em um recebimento, na
+// unidade de compra do item, opcionalmente com lote, validade e o local onde foi
+// guardado.
+type PurchaseReceiptItem struct {
+	ProductID  int64   `json:"product_id" binding:"required"`
+	Quantidade float64 `json:"quantidade" binding:"required,gt=0"`
+	Lote       string  `json:"lote"`
+	Validade   string  `json:"validade"`
+	LocationID *int64  `json:"location_id"`
+}
+
+// PurchaseReceipt descreve um recebimento. Sem itens, todo o saldo pendente
+// do pedido é recebido.
+type PurchaseReceipt struct {
+	Itens []PurchaseReceiptItem `json:"itens" binding:"dive"`
+}
+
+const purchaseOrderColumns = `id, supplier_id, estabelecimento_id, status, observacao, valor_total, criado_por, aprovado_por,
+	aprovado_em, enviado_em, recebido_em, cancelado_em, created_at, updated_at`
+
+var PurchaseOrderSortColumns = map[string]string{
+	"id":          "id",
+	"status":      "status",
+	"valor_total": "valor_total",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
+
+func scanPurchaseOrder(row rowScanner, po *PurchaseOrder) error {
+	return row.Scan(
+		&po.ID, &po.SupplierID, &po.EstabelecimentoID, &po.Status, &po.Observacao, &po.ValorTotal, &po.CriadoPor, &po.AprovadoPor,
+		&po.AprovadoEm, &po.EnviadoEm, &po.RecebidoEm, &po.CanceladoEm, &po.CreatedAt, &po.UpdatedAt,
+	)
+}
+
+// PurchaseApprovalLimit lê de PURCHASE_ORDER_APPROVAL_LIMIT o valor acima do
+// qual um pedido de compra só pode ser enviado com aprovação de um OWNER.
+func PurchaseApprovalLimit() float64 {
+	limit, err := strconv.ParseFloat(os.Getenv("PURCHASE_ORDER_APPROVAL_LIMIT"), 64)
+	if err != nil || limit < 0 {
+		return DefaultPurchaseApprovalLimit
+	}
+
+	return limit
+}
+
+// RequiresApproval indica se o pedido ainda precisa da aprovação de um OWNER
+// para ser enviado.
+func (po *PurchaseOrder) RequiresApproval() bool {
+	return po.ValorTotal > PurchaseApprovalLimit() && po.AprovadoPor == nil
+}
+
+// saveItems valida e grava os itens do pedido, que precisam ser produtos
+// ativos do mesmo estabelecimento, e recalcula o valor total.
+func (po *PurchaseOrder) saveItems(tx *sql.Tx) error {
+	seen := map[int64]bool{}
+	total := 0.0
+
+	for i := range po.Itens {
+		item := &po.Itens[i]
+
+		if seen[item.ProductID] || item.Quantidade <= 0 || item.CustoUnitario < 0 {
+			return ErrInvalidPurchaseOrderItem
+		}
+		seen[item.ProductID] = true
+
+		var productEstabId int64
+		err := tx.QueryRow(
+			"SELECT estabelecimento_id, nome, unidade_compra, fator_compra FROM products WHERE id = $1 AND deleted_at IS NULL",
+			item.ProductID,
+		).Scan(&productEstabId, &item.ProductNome, &item.Unidade, &item.Fator)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidPurchaseOrderItem
+		}
+		if err != nil {
+			return err
+		}
+
+		if productEstabId != po.EstabelecimentoID {
+			return ErrInvalidPurchaseOrderItem
+		}
+
+		item.Quantidade = roundQuantity(item.Quantidade)
+		item.QuantidadeRecebida = 0
+
+		err = checkUnitQuantity(item.Unidade, item.Quantidade)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(
+			`INSERT INTO purchase_order_items(purchase_order_id, product_id, quantidade, unidade, fator, custo_unitario)
+			VALUES($1, $2, $3, $4, $5, $6) RETURNING id`,
+			po.ID, item.ProductID, item.Quantidade, item.Unidade, item.Fator, item.CustoUnitario,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+
+		total += item.Quantidade * item.CustoUnitario
+	}
+
+	po.ValorTotal = roundMoney(total)
+
+	_, err := tx.Exec("UPDATE purchase_orders SET valor_total = $1 WHERE id = $2", po.ValorTotal, po.ID)
+
+	return err
+}
+
+// Save cria o pedido como rascunho no estabelecimento do fornecedor.
+func (po *PurchaseOrder) Save(tx *sql.Tx, role string, userId int64) error {
+	err := tx.QueryRow("SELECT estabelecimento_id FROM suppliers WHERE id = $1", po.SupplierID).Scan(&po.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), po.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	po.Status = PurchaseOrderRascunho
+	po.CriadoPor = &userId
+
+	query := `INSERT INTO purchase_orders(supplier_id, estabelecimento_id, status, observacao, criado_por)
+	VALUES($1, $2, $3, $4, $5)
+	RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(query, po.SupplierID, po.EstabelecimentoID, po.Status, po.Observacao, userId).Scan(&po.ID, &po.CreatedAt, &po.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return po.saveItems(tx)
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func getPurchaseOrderItems(q queryer, purchaseOrderId int64) ([]PurchaseOrderItem, error) {
+	rows, err := q.Query(`SELECT i.id, i.product_id, p.nome, i.quantidade, i.quantidade_recebida, i.unidade, i.fator, i.custo_unitario
+	FROM purchase_order_items i
+	JOIN products p ON p.id = i.product_id
+	WHERE i.purchase_order_id = $1
+	ORDER BY i.id`, purchaseOrderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []PurchaseOrderItem{}
+
+	for rows.Next() {
+		var item PurchaseOrderItem
+		err := rows.Scan(&item.ID, &item.ProductID, &item.ProductNome, &item.Quantidade, &item.QuantidadeRecebida, &item.Unidade, &item.Fator, &item.CustoUnitario)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func GetAllPurchaseOrders(role, userId string, filter PurchaseOrderFilter, params ListParams) ([]PurchaseOrder, ListResult, error) {
+	baseQuery := "SELECT " + purchaseOrderColumns + " FROM purchase_orders WHERE 1=1"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		baseQuery += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if supplierId, err := strconv.ParseInt(filter.SupplierID, 10, 64); err == nil {
+		args = append(args, supplierId)
+		baseQuery += fmt.Sprintf(" AND supplier_id = $%d", len(args))
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var orders []PurchaseOrder
+
+	for rows.Next() {
+		var order PurchaseOrder
+		err := scanPurchaseOrder(rows, &order)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		orders = append(orders, order)
+	}
+
+	fetched := len(orders)
+	if fetched > params.PerPage {
+		orders = orders[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(orders) > 0 {
+		lastID = orders[len(orders)-1].ID
+	}
+
+	return orders, params.result(total, fetched, lastID), nil
+}
+
+func GetPurchaseOrder(id int64, role, userId string) (*PurchaseOrder, error) {
+	row := db.DB.QueryRow("SELECT "+purchaseOrderColumns+" FROM purchase_orders WHERE id = $1", id)
+
+	var order PurchaseOrder
+
+	err := scanPurchaseOrder(row, &order)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, order.EstabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	order.Itens, err = getPurchaseOrderItems(db.DB, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (po *PurchaseOrder) lock(tx *sql.Tx) error {
+	row := tx.QueryRow("SELECT "+purchaseOrderColumns+" FROM purchase_orders WHERE id = $1 FOR UPDATE", po.ID)
+	return scanPurchaseOrder(row, po)
+}
+
+// Update substitui fornecedor, observação e itens de um rascunho. Como o
+// valor pode mudar, uma aprovação anterior é descartada.
+func (po *PurchaseOrder) Update(tx *sql.Tx, role string, userId int64) error {
+	items := po.Itens
+	observacao := po.Observacao
+	supplierId := po.SupplierID
+
+	err := po.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if po.Status != PurchaseOrderRascunho {
+		return ErrInvalidPurchaseOrderStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), po.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	var supplierEstabId int64
+	err = tx.QueryRow("SELECT estabelecimento_id FROM suppliers WHERE id = $1", supplierId).Scan(&supplierEstabId)
+	if err != nil {
+		return err
+	}
+
+	if supplierEstabId != po.EstabelecimentoID {
+		return ErrSupplierEstablishmentMismatch
+	}
+
+	po.Itens = items
+	po.Observacao = observacao
+	po.SupplierID = supplierId
+	po.AprovadoPor = nil
+	po.AprovadoEm = nil
+	po.UpdatedAt = time.Now()
+
+	_, err = tx.Exec("DELETE FROM purchase_order_items WHERE purchase_order_id = $1", po.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE purchase_orders SET supplier_id = $1, observacao = $2, aprovado_por = NULL, aprovado_em = NULL, updated_at = $3 WHERE id = $4",
+		po.SupplierID, po.Observacao, po.UpdatedAt, po.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return po.saveItems(tx)
+}
+
+// Approve registra a aprovação de um OWNER para um rascunho.
+func (po *PurchaseOrder) Approve(tx *sql.Tx, role string, userId int64) error {
+	err := po.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if po.Status != PurchaseOrderRascunho {
+		return ErrInvalidPurchaseOrderStatus
+	}
+
+	if role != "OWNER" {
+		return ErrAccessDenied
+	}
+
+	now := time.Now()
+	po.AprovadoPor = &userId
+	po.AprovadoEm = &now
+	po.UpdatedAt = now
+
+	_, err = tx.Exec(
+		"UPDATE purchase_orders SET aprovado_por = $1, aprovado_em = $2, updated_at = $2 WHERE id = $3",
+		userId, now, po.ID,
+	)
+
+	return err
+}
+
+// Send envia o rascunho ao fornecedor. Pedidos acima do limite precisam de
+// aprovação; quando quem envia é um OWNER o envio já vale como aprovação.
+func (po *PurchaseOrder) Send(tx *sql.Tx, role string, userId int64) error {
+	err := po.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if po.Status != PurchaseOrderRascunho {
+		return ErrInvalidPurchaseOrderStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), po.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	if po.RequiresApproval() {
+		if role != "OWNER" {
+			return ErrPurchaseApprovalRequired
+		}
+
+		po.AprovadoPor = &userId
+		po.AprovadoEm = &now
+	}
+
+	po.Status = PurchaseOrderEnviado
+	po.EnviadoEm = &now
+	po.UpdatedAt = now
+
+	_, err = tx.Exec(
+		"UPDATE purchase_orders SET status = $1, aprovado_por = $2, aprovado_em = $3, enviado_em = $4, updated_at = $4 WHERE id = $5",
+		po.Status, po.AprovadoPor, po.AprovadoEm, now, po.ID,
+	)
+
+	return err
+}
+
+// Receive dá entrada no estoque das quantidades recebidas, na mesma transação
+// que atualiza os itens e o status do pedido. O custo do item passa a ser o
+// último custo do produto com o fornecedor.
+func (po *PurchaseOrder) Receive(tx *sql.Tx, role string, userId int64, receipt PurchaseReceipt) error {
+	err := po.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if po.Status != PurchaseOrderEnviado && po.Status != PurchaseOrderParcialmenteRecebido {
+		return ErrInvalidPurchaseOrderStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), po.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	items, err := getPurchaseOrderItems(tx, po.ID)
+	if err != nil {
+		return err
+	}
+
+	byProduct := map[int64]*PurchaseOrderItem{}
+	for i := range items {
+		byProduct[items[i].ProductID] = &items[i]
+	}
+
+	if len(receipt.Itens) == 0 {
+		for _, item := range items {
+			pendente := roundQuantity(item.Quantidade - item.QuantidadeRecebida)
+			if pendente > 0 {
+				receipt.Itens = append(receipt.Itens, PurchaseReceiptItem{ProductID: item.ProductID, Quantidade: pendente})
+			}
+		}
+	}
+
+	productIds := make([]int64, 0, len(receipt.Itens))
+	for _, received := range receipt.Itens {
+		productIds = append(productIds, received.ProductID)
+	}
+
+	err = lockProducts(tx, productIds)
+	if err != nil {
+		return err
+	}
+
+	for _, received := range receipt.Itens {
+		item, ok := byProduct[received.ProductID]
+		if !ok {
+			return ErrInvalidPurchaseOrderItem
+		}
+
+		quantidade := roundQuantity(received.Quantidade)
+		if quantidade <= 0 {
+			return ErrInvalidQuantity
+		}
+
+		err = checkUnitQuantity(item.Unidade, quantidade)
+		if err != nil {
+			return err
+		}
+
+		if roundQuantity(item.QuantidadeRecebida+quantidade) > item.Quantidade {
+			return ErrReceiptExceedsOrdered
+		}
+
+		movement := StockMovement{
+			ProductID:  item.ProductID,
+			Tipo:       MovementEntrada,
+			Quantidade: roundQuantity(quantidade * item.Fator),
+			Motivo:     fmt.Sprintf("Recebimento do pedido de compra #%d", po.ID),
+			Lote:       received.Lote,
+			Validade:   received.Validade,
+			LocationID: received.LocationID,
+			UserID:     userId,
+		}
+
+		// O custo do item é por unidade de compra.
+		custo := item.CustoUnitario / item.Fator
+		movement.CustoUnitario = &custo
+
+		err = movement.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		item.QuantidadeRecebida = roundQuantity(item.QuantidadeRecebida + quantidade)
+
+		_, err = tx.Exec("UPDATE purchase_order_items SET quantidade_recebida = $1 WHERE id = $2", item.QuantidadeRecebida, item.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO product_suppliers(product_id, supplier_id, ultimo_custo)
+		VALUES($1, $2, $3)
+		ON CONFLICT (product_id, supplier_id) DO UPDATE SET ultimo_custo = EXCLUDED.ultimo_custo, updated_at = NOW()`,
+			item.ProductID, po.SupplierID, item.CustoUnitario,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	po.Status = PurchaseOrderRecebido
+	po.UpdatedAt = now
+
+	for _, item := range items {
+		if item.QuantidadeRecebida < item.Quantidade {
+			po.Status = PurchaseOrderParcialmenteRecebido
+			break
+		}
+	}
+
+	if po.Status == PurchaseOrderRecebido {
+		po.RecebidoEm = &now
+	}
+
+	po.Itens = items
+
+	_, err = tx.Exec(
+		"UPDATE purchase_orders SET status = $1, recebido_em = $2, updated_at = $3 WHERE id = $4",
+		po.Status, po.RecebidoEm, now, po.ID,
+	)
+
+	return err
+}
+
+// Cancel encerra o pedido. Quantidades já recebidas permanecem no estoque.
+func (po *PurchaseOrder) Cancel(tx *sql.Tx, role string, userId int64) error {
+	err := po.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if po.Status == PurchaseOrderRecebido || po.Status == PurchaseOrderCancelado {
+		return ErrInvalidPurchaseOrderStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), po.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	po.Status = PurchaseOrderCancelado
+	po.CanceladoEm = &now
+	po.UpdatedAt = now
+
+	_, err = tx.Exec(
+		"UPDATE purchase_orders SET status = $1, cancelado_em = $2, updated_at = $2 WHERE id = $3",
+		po.Status, now, po.ID,
+	)
+
+	return err
+}
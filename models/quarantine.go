@@ -0,0 +1,143 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	QuarantineEntrada   = "ENTRADA"
+	QuarantineBaixa     = "BAIXA"
+	QuarantineReestoque = "REESTOQUE"
+)
+
+var ErrInsufficientQuarantine = errors.New("quantidade insuficiente em quarentena")
+
+// QuarantineMovement registra as alterações no estoque avariado do produto,
+// que fica separado do estoque vendável até ser baixado ou reestocado.
+type QuarantineMovement struct {
+	ID               int64     `json:"id"`
+	ProductID        int64     `json:"product_id"`
+	Tipo             string    `json:"tipo"`
+	Quantidade       float64   `json:"quantidade" binding:"required,gt=0"`
+	Motivo           string    `json:"motivo" binding:"required"`
+	EstoqueAnterior  float64   `json:"estoque_anterior"`
+	EstoquePosterior float64   `json:"estoque_posterior"`
+	ReturnID         *int64    `json:"return_id"`
+	UserID           int64     `json:"user_id"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Save aplica a movimentação ao estoque avariado com a linha do produto
+// bloqueada. Um reestoque também gera a entrada no estoque vendável.
+func (q *QuarantineMovement) Save(tx *sql.Tx) error {
+	quantidade := roundQuantity(q.Quantidade)
+	if quantidade <= 0 {
+		return ErrInvalidQuantity
+	}
+	q.Quantidade = quantidade
+
+	var delta float64
+	switch q.Tipo {
+	case QuarantineEntrada:
+		delta = quantidade
+	case QuarantineBaixa, QuarantineReestoque:
+		delta = -quantidade
+	default:
+		return ErrInvalidQuantity
+	}
+
+	err := lockProducts(tx, []int64{q.ProductID})
+	if err != nil {
+		return err
+	}
+
+	var unidade string
+	err = tx.QueryRow("SELECT estoque_avariado, unidade FROM products WHERE id = $1 FOR UPDATE", q.ProductID).Scan(&q.EstoqueAnterior, &unidade)
+	if err != nil {
+		return err
+	}
+
+	err = checkUnitQuantity(unidade, quantidade)
+	if err != nil {
+		return err
+	}
+
+	q.EstoquePosterior = roundQuantity(q.EstoqueAnterior + delta)
+	if q.EstoquePosterior < 0 {
+		return ErrInsufficientQuarantine
+	}
+
+	var userId interface{}
+	if q.UserID != 0 {
+		userId = q.UserID
+	}
+
+	query := `INSERT INTO quarantine_movements(product_id, tipo, quantidade, motivo, estoque_anterior, estoque_posterior, return_id, user_id)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at`
+
+	err = tx.QueryRow(
+		query,
+		q.ProductID, q.Tipo, q.Quantidade, q.Motivo, q.EstoqueAnterior, q.EstoquePosterior, q.ReturnID, userId,
+	).Scan(&q.ID, &q.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE products SET estoque_avariado = $1, updated_at = $2 WHERE id = $3", q.EstoquePosterior, q.CreatedAt, q.ProductID)
+	if err != nil {
+		return err
+	}
+
+	if q.Tipo != QuarantineReestoque {
+		return nil
+	}
+
+	movement := StockMovement{
+		ProductID:  q.ProductID,
+		Tipo:       MovementEntrada,
+		Quantidade: quantidade,
+		Motivo:     fmt.Sprintf("Reestoque de avariados: %s", q.Motivo),
+		UserID:     q.UserID,
+	}
+
+	return movement.Save(tx)
+}
+
+func GetQuarantineMovements(productId int64) ([]QuarantineMovement, error) {
+	query := `SELECT id, product_id, tipo, quantidade, motivo, estoque_anterior, estoque_posterior, return_id, COALESCE(user_id, 0), created_at
+	FROM quarantine_movements
+	WHERE product_id = $1
+	ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.DB.Query(query, productId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var movements []QuarantineMovement
+
+	for rows.Next() {
+		var movement QuarantineMovement
+		err := rows.Scan(
+			&movement.ID, &movement.ProductID, &movement.Tipo, &movement.Quantidade, &movement.Motivo,
+			&movement.EstoqueAnterior, &movement.EstoquePosterior, &movement.ReturnID, &movement.UserID, &movement.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		movements = append(movements, movement)
+	}
+
+	return movements, nil
+}
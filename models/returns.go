@@ -0,0 +1,315 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	ReturnDestinoEstoque  = "ESTOQUE"
+	ReturnDestinoAvariado = "AVARIADO"
+)
+
+var (
+	ErrInvalidReturnItem = errors.New("item inválido para a devolução")
+	ErrReturnExceedsSold = errors.New("a quantidade devolvida excede a quantidade vendida")
+)
+
+type ReturnItem struct {
+	ID         int64   `json:"id"`
+	ProductID  int64   `json:"product_id" binding:"required"`
+	Quantidade float64 `json:"quantidade" binding:"required,gt=0"`
+	Destino    string  `json:"destino" binding:"required,oneof=ESTOQUE AVARIADO"`
+}
+
+// Return é a devolução de mercadorias por um cliente. Quando vinculada a uma
+// venda, os itens precisam ter sido vendidos nela.
+type Return struct {
+	ID                int64        `json:"id"`
+	EstabelecimentoID int64        `json:"estabelecimento_id"`
+	SaleID            *int64       `json:"sale_id"`
+	Motivo            string       `json:"motivo" binding:"required"`
+	DocumentoCliente  string       `json:"documento_cliente"`
+	UserID            *int64       `json:"user_id"`
+	Itens             []ReturnItem `json:"itens" binding:"required,min=1,dive"`
+	CreatedAt         time.Time    `json:"created_at"`
+}
+
+type ReturnFilter struct {
+	SaleID           string
+	DocumentoCliente string
+}
+
+const returnColumns = "id, estabelecimento_id, sale_id, motivo, documento_cliente, user_id, created_at"
+
+var ReturnSortColumns = map[string]string{
+	"id":         "id",
+	"created_at": "created_at",
+}
+
+func scanReturn(row rowScanner, r *Return) error {
+	return row.Scan(&r.ID, &r.EstabelecimentoID, &r.SaleID, &r.Motivo, &r.DocumentoCliente, &r.UserID, &r.CreatedAt)
+}
+
+// saleItemForReturn devolve a movimentação de saída do item na venda e quanto
+// dele ainda pode ser devolvido, na unidade de estoque.
+func saleItemForReturn(tx *sql.Tx, saleId, productId int64) (int64, float64, error) {
+	var movementId sql.NullInt64
+	var vendido, devolvido float64
+
+	err := tx.QueryRow(
+		"SELECT movement_id, quantidade * fator FROM sale_items WHERE sale_id = $1 AND product_id = $2",
+		saleId, productId,
+	).Scan(&movementId, &vendido)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, ErrInvalidReturnItem
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = tx.QueryRow(`SELECT COALESCE(SUM(i.quantidade), 0)
+	FROM return_items i
+	JOIN returns r ON r.id = i.return_id
+	WHERE r.sale_id = $1 AND i.product_id = $2`, saleId, productId).Scan(&devolvido)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return movementId.Int64, roundQuantity(vendido - devolvido), nil
+}
+
+// Save registra a devolução e, na mesma transação, devolve cada item ao
+// estoque vendável ou à quarentena de avariados do produto.
+func (r *Return) Save(tx *sql.Tx, role string, userId int64) error {
+	userIdStr := fmt.Sprintf("%d", userId)
+
+	if r.SaleID != nil {
+		var status string
+		err := tx.QueryRow("SELECT estabelecimento_id, status FROM sales WHERE id = $1 FOR UPDATE", *r.SaleID).Scan(&r.EstabelecimentoID, &status)
+		if err != nil {
+			return err
+		}
+
+		if status != SaleConcluida {
+			return ErrInvalidSaleStatus
+		}
+	} else if r.EstabelecimentoID == 0 {
+		estabelecimentoId, err := GetUserEstablishmentID(userIdStr)
+		if err != nil {
+			return err
+		}
+		r.EstabelecimentoID = estabelecimentoId
+	}
+
+	err := CheckEstablishmentAccess(role, userIdStr, r.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	r.UserID = &userId
+
+	productIds := make([]int64, 0, len(r.Itens))
+	for _, item := range r.Itens {
+		productIds = append(productIds, item.ProductID)
+	}
+
+	err = lockProducts(tx, productIds)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO returns(estabelecimento_id, sale_id, motivo, documento_cliente, user_id)
+	VALUES($1, $2, $3, $4, $5)
+	RETURNING id, created_at`
+
+	err = tx.QueryRow(query, r.EstabelecimentoID, r.SaleID, r.Motivo, r.DocumentoCliente, userId).Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	motivo := fmt.Sprintf("Devolução #%d: %s", r.ID, r.Motivo)
+
+	for i := range r.Itens {
+		item := &r.Itens[i]
+		item.Quantidade = roundQuantity(item.Quantidade)
+
+		var productEstabId int64
+		err := tx.QueryRow("SELECT estabelecimento_id FROM products WHERE id = $1", item.ProductID).Scan(&productEstabId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidReturnItem
+		}
+		if err != nil {
+			return err
+		}
+
+		if productEstabId != r.EstabelecimentoID {
+			return ErrInvalidReturnItem
+		}
+
+		var saleMovementId int64
+		if r.SaleID != nil {
+			var pendente float64
+			saleMovementId, pendente, err = saleItemForReturn(tx, *r.SaleID, item.ProductID)
+			if err != nil {
+				return err
+			}
+
+			if item.Quantidade > pendente {
+				return ErrReturnExceedsSold
+			}
+		}
+
+		err = tx.QueryRow(
+			"INSERT INTO return_items(return_id, product_id, quantidade, destino) VALUES($1, $2, $3, $4) RETURNING id",
+			r.ID, item.ProductID, item.Quantidade, item.Destino,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+
+		switch item.Destino {
+		case ReturnDestinoEstoque:
+			_, err = restockFromMovement(tx, saleMovementId, item.ProductID, item.Quantidade, motivo, userId)
+		case ReturnDestinoAvariado:
+			quarantine := QuarantineMovement{
+				ProductID:  item.ProductID,
+				Tipo:       QuarantineEntrada,
+				Quantidade: item.Quantidade,
+				Motivo:     motivo,
+				ReturnID:   &r.ID,
+				UserID:     userId,
+			}
+			err = quarantine.Save(tx)
+		default:
+			err = ErrInvalidReturnItem
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getReturnItems(q queryer, returnId int64) ([]ReturnItem, error) {
+	rows, err := q.Query("SELECT id, product_id, quantidade, destino FROM return_items WHERE return_id = $1 ORDER BY id", returnId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []ReturnItem{}
+
+	for rows.Next() {
+		var item ReturnItem
+		err := rows.Scan(&item.ID, &item.ProductID, &item.Quantidade, &item.Destino)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func GetAllReturns(role, userId string, filter ReturnFilter, params ListParams) ([]Return, ListResult, error) {
+	baseQuery := "SELECT " + returnColumns + " FROM returns WHERE 1=1"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	if saleId, err := strconv.ParseInt(filter.SaleID, 10, 64); err == nil {
+		args = append(args, saleId)
+		baseQuery += fmt.Sprintf(" AND sale_id = $%d", len(args))
+	}
+	if filter.DocumentoCliente != "" {
+		args = append(args, filter.DocumentoCliente)
+		baseQuery += fmt.Sprintf(" AND documento_cliente = $%d", len(args))
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var returns []Return
+
+	for rows.Next() {
+		var r Return
+		err := scanReturn(rows, &r)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		returns = append(returns, r)
+	}
+
+	fetched := len(returns)
+	if fetched > params.PerPage {
+		returns = returns[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(returns) > 0 {
+		lastID = returns[len(returns)-1].ID
+	}
+
+	return returns, params.result(total, fetched, lastID), nil
+}
+
+func GetReturn(id int64, role, userId string) (*Return, error) {
+	row := db.DB.QueryRow("SELECT "+returnColumns+" FROM returns WHERE id = $1", id)
+
+	var r Return
+
+	err := scanReturn(row, &r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, r.EstabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Itens, err = getReturnItems(db.DB, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
@@ -0,0 +1,463 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	SaleConcluida = "CONCLUIDA"
+	SaleCancelada = "CANCELADA"
+)
+
+var (
+	ErrInvalidSaleItem   = errors.New("item inválido para a venda")
+	ErrInvalidDiscount   = errors.New("o desconto não pode ser maior que o valor")
+	ErrInvalidSaleStatus = errors.New("a venda não está em um status que permita essa operação")
+)
+
+// SaleItem é vendido na unidade de venda do produto. Fator converte a
+// quantidade para a unidade de estoque.
+type SaleItem struct {
+	ID            int64   `json:"id"`
+	ProductID     int64   `json:"product_id" binding:"required"`
+	ProductNome   string  `json:"product_nome"`
+	Quantidade    float64 `json:"quantidade" binding:"required,gt=0"`
+	Unidade       string  `json:"unidade"`
+	Fator         float64 `json:"fator"`
+	ValorUnitario float64 `json:"valor_unitario"`
+	Desconto      float64 `json:"desconto" binding:"gte=0"`
+	Total         float64 `json:"total"`
+	MovementID    *int64  `json:"movement_id"`
+}
+
+type Sale struct {
+	ID                 int64      `json:"id"`
+	EstabelecimentoID  int64      `json:"estabelecimento_id"`
+	Status             string     `json:"status"`
+	FormaPagamento     string     `json:"forma_pagamento" binding:"required,oneof=DINHEIRO CARTAO_CREDITO CARTAO_DEBITO PIX BOLETO"`
+	Subtotal           float64    `json:"subtotal"`
+	Desconto           float64    `json:"desconto" binding:"gte=0"`
+	Total              float64    `json:"total"`
+	Observacao         string     `json:"observacao"`
+	VendedorID         *int64     `json:"vendedor_id"`
+	CanceladoPor       *int64     `json:"cancelado_por"`
+	CanceladoEm        *time.Time `json:"cancelado_em"`
+	MotivoCancelamento string     `json:"motivo_cancelamento"`
+	Itens              []SaleItem `json:"itens" binding:"required,min=1,dive"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type SaleFilter struct {
+	Status         string
+	FormaPagamento string
+	VendedorID     string
+	StartDate      string
+	EndDate        string
+}
+
+const saleColumns = `id, estabelecimento_id, status, forma_pagamento, subtotal, desconto, total, observacao, vendedor_id,
+	cancelado_por, cancelado_em, motivo_cancelamento, created_at, updated_at`
+
+var SaleSortColumns = map[string]string{
+	"id":         "id",
+	"total":      "total",
+	"created_at": "created_at",
+}
+
+func scanSale(row rowScanner, s *Sale) error {
+	return row.Scan(
+		&s.ID, &s.EstabelecimentoID, &s.Status, &s.FormaPagamento, &s.Subtotal, &s.Desconto, &s.Total, &s.Observacao, &s.VendedorID,
+		&s.CanceladoPor, &s.CanceladoEm, &s.MotivoCancelamento, &s.CreatedAt, &s.UpdatedAt,
+	)
+}
+
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// calculateTotals preenche o valor unitário e o total de cada item e os totais
+// da venda, arredondando cada passo em centavos. valores traz o preço de cada
+// produto por unidade de estoque, e o fator de venda dos itens já deve estar
+// preenchido. Um desconto maior que o valor do item ou que o subtotal falha
+// com ErrInvalidDiscount.
+func (s *Sale) calculateTotals(valores map[int64]float64) error {
+	s.Subtotal = 0
+	for i := range s.Itens {
+		item := &s.Itens[i]
+
+		// O valor do produto é por unidade de estoque.
+		item.ValorUnitario = roundMoney(valores[item.ProductID] * item.Fator)
+
+		bruto := roundMoney(item.Quantidade * item.ValorUnitario)
+		if item.Desconto > bruto {
+			return ErrInvalidDiscount
+		}
+
+		item.Total = roundMoney(bruto - item.Desconto)
+		s.Subtotal = roundMoney(s.Subtotal + item.Total)
+	}
+
+	if s.Desconto > s.Subtotal {
+		return ErrInvalidDiscount
+	}
+
+	s.Total = roundMoney(s.Subtotal - s.Desconto)
+
+	return nil
+}
+
+// Save registra a venda e dá baixa no estoque de cada item na mesma transação.
+// Antes de qualquer baixa, lockProducts bloqueia os produtos e os componentes
+// dos kits em ordem de id, o que impede que duas vendas simultâneas vendam o
+// mesmo saldo e evita deadlocks.
+func (s *Sale) Save(tx *sql.Tx, role string, userId int64) error {
+	userIdStr := fmt.Sprintf("%d", userId)
+
+	if s.EstabelecimentoID == 0 {
+		estabelecimentoId, err := GetUserEstablishmentID(userIdStr)
+		if err != nil {
+			return err
+		}
+		s.EstabelecimentoID = estabelecimentoId
+	}
+
+	err := CheckEstablishmentAccess(role, userIdStr, s.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	var permiteNegativo bool
+	err = tx.QueryRow(
+		"SELECT permite_estoque_negativo FROM estabelecimentos WHERE id = $1 AND deleted_at IS NULL",
+		s.EstabelecimentoID,
+	).Scan(&permiteNegativo)
+	if err != nil {
+		return err
+	}
+
+	productIds := make([]int64, 0, len(s.Itens))
+	seen := map[int64]bool{}
+	for _, item := range s.Itens {
+		if seen[item.ProductID] {
+			return ErrInvalidSaleItem
+		}
+		seen[item.ProductID] = true
+		productIds = append(productIds, item.ProductID)
+	}
+	sort.Slice(productIds, func(i, j int) bool { return productIds[i] < productIds[j] })
+
+	err = lockProducts(tx, productIds)
+	if err != nil {
+		return err
+	}
+
+	type lockedProduct struct {
+		nome    string
+		valor   float64
+		unidade string
+		fator   float64
+	}
+	products := map[int64]lockedProduct{}
+
+	for _, productId := range productIds {
+		var product lockedProduct
+		var estabelecimentoId int64
+		err := tx.QueryRow(
+			"SELECT nome, valor, unidade_venda, fator_venda, estabelecimento_id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+			productId,
+		).Scan(&product.nome, &product.valor, &product.unidade, &product.fator, &estabelecimentoId)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidSaleItem
+		}
+		if err != nil {
+			return err
+		}
+
+		if estabelecimentoId != s.EstabelecimentoID {
+			return ErrInvalidSaleItem
+		}
+
+		products[productId] = product
+	}
+
+	valores := map[int64]float64{}
+	for i := range s.Itens {
+		item := &s.Itens[i]
+		product := products[item.ProductID]
+
+		item.Quantidade = roundQuantity(item.Quantidade)
+		item.ProductNome = product.nome
+		item.Unidade = product.unidade
+		item.Fator = product.fator
+		valores[item.ProductID] = product.valor
+
+		err := checkUnitQuantity(item.Unidade, item.Quantidade)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.calculateTotals(valores)
+	if err != nil {
+		return err
+	}
+
+	s.Status = SaleConcluida
+	s.VendedorID = &userId
+
+	query := `INSERT INTO sales(estabelecimento_id, status, forma_pagamento, subtotal, desconto, total, observacao, vendedor_id)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(
+		query,
+		s.EstabelecimentoID, s.Status, s.FormaPagamento, s.Subtotal, s.Desconto, s.Total, s.Observacao, userId,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range s.Itens {
+		item := &s.Itens[i]
+
+		movement := StockMovement{
+			ProductID:              item.ProductID,
+			Tipo:                   MovementSaida,
+			Quantidade:             roundQuantity(item.Quantidade * item.Fator),
+			Motivo:                 fmt.Sprintf("Venda #%d", s.ID),
+			UserID:                 userId,
+			PermiteEstoqueNegativo: permiteNegativo,
+		}
+
+		err = movement.Save(tx)
+		if err != nil {
+			return err
+		}
+
+		item.MovementID = &movement.ID
+
+		err = tx.QueryRow(
+			`INSERT INTO sale_items(sale_id, product_id, quantidade, unidade, fator, valor_unitario, desconto, total, movement_id)
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+			s.ID, item.ProductID, item.Quantidade, item.Unidade, item.Fator, item.ValorUnitario, item.Desconto, item.Total, movement.ID,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getSaleItems(q queryer, saleId int64) ([]SaleItem, error) {
+	rows, err := q.Query(`SELECT i.id, i.product_id, p.nome, i.quantidade, i.unidade, i.fator, i.valor_unitario, i.desconto, i.total, i.movement_id
+	FROM sale_items i
+	JOIN products p ON p.id = i.product_id
+	WHERE i.sale_id = $1
+	ORDER BY i.id`, saleId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []SaleItem{}
+
+	for rows.Next() {
+		var item SaleItem
+		err := rows.Scan(&item.ID, &item.ProductID, &item.ProductNome, &item.Quantidade, &item.Unidade, &item.Fator, &item.ValorUnitario, &item.Desconto, &item.Total, &item.MovementID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func GetAllSales(role, userId string, filter SaleFilter, params ListParams) ([]Sale, ListResult, error) {
+	baseQuery := "SELECT " + saleColumns + " FROM sales WHERE 1=1"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		baseQuery += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.FormaPagamento != "" {
+		args = append(args, filter.FormaPagamento)
+		baseQuery += fmt.Sprintf(" AND forma_pagamento = $%d", len(args))
+	}
+	if vendedorId, err := strconv.ParseInt(filter.VendedorID, 10, 64); err == nil {
+		args = append(args, vendedorId)
+		baseQuery += fmt.Sprintf(" AND vendedor_id = $%d", len(args))
+	}
+	if filter.StartDate != "" && filter.EndDate != "" {
+		layoutBR := "02/01/2006"
+		startDate, err1 := time.Parse(layoutBR, filter.StartDate)
+		endDate, err2 := time.Parse(layoutBR, filter.EndDate)
+		if err1 == nil && err2 == nil {
+			endDate = endDate.Add(time.Hour*23 + time.Minute*59 + time.Second*59)
+
+			args = append(args, startDate, endDate)
+			baseQuery += fmt.Sprintf(" AND created_at BETWEEN $%d AND $%d", len(args)-1, len(args))
+		}
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var sales []Sale
+
+	for rows.Next() {
+		var sale Sale
+		err := scanSale(rows, &sale)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		sales = append(sales, sale)
+	}
+
+	fetched := len(sales)
+	if fetched > params.PerPage {
+		sales = sales[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(sales) > 0 {
+		lastID = sales[len(sales)-1].ID
+	}
+
+	return sales, params.result(total, fetched, lastID), nil
+}
+
+func GetSale(id int64, role, userId string) (*Sale, error) {
+	row := db.DB.QueryRow("SELECT "+saleColumns+" FROM sales WHERE id = $1", id)
+
+	var sale Sale
+
+	err := scanSale(row, &sale)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, sale.EstabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	sale.Itens, err = getSaleItems(db.DB, sale.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sale, nil
+}
+
+func (s *Sale) lock(tx *sql.Tx) error {
+	row := tx.QueryRow("SELECT "+saleColumns+" FROM sales WHERE id = $1 FOR UPDATE", s.ID)
+	return scanSale(row, s)
+}
+
+// Cancel estorna a venda devolvendo ao estoque, e aos lotes de onde saíram,
+// as quantidades de cada item que ainda não foram devolvidas.
+func (s *Sale) Cancel(tx *sql.Tx, role string, userId int64, motivo string) error {
+	err := s.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if s.Status != SaleConcluida {
+		return ErrInvalidSaleStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), s.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	s.Itens, err = getSaleItems(tx, s.ID)
+	if err != nil {
+		return err
+	}
+
+	productIds := make([]int64, 0, len(s.Itens))
+	for _, item := range s.Itens {
+		productIds = append(productIds, item.ProductID)
+	}
+
+	err = lockProducts(tx, productIds)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range s.Itens {
+		// Quantidades já devolvidas voltaram ao estoque pela devolução.
+		movementId, pendente, err := saleItemForReturn(tx, s.ID, item.ProductID)
+		if err != nil {
+			return err
+		}
+
+		if pendente <= 0 {
+			continue
+		}
+
+		_, err = restockFromMovement(tx, movementId, item.ProductID, pendente, fmt.Sprintf("Cancelamento da venda #%d", s.ID), userId)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	s.Status = SaleCancelada
+	s.CanceladoPor = &userId
+	s.CanceladoEm = &now
+	s.MotivoCancelamento = motivo
+	s.UpdatedAt = now
+
+	_, err = tx.Exec(
+		"UPDATE sales SET status = $1, cancelado_por = $2, cancelado_em = $3, motivo_cancelamento = $4, updated_at = $3 WHERE id = $5",
+		s.Status, userId, now, motivo, s.ID,
+	)
+
+	return err
+}
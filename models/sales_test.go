@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSaleCalculateTotals(t *testing.T) {
+	tests := []struct {
+		name     string
+		itens    []SaleItem
+		valores  map[int64]float64
+		desconto float64
+		unitario []float64
+		totais   []float64
+		subtotal float64
+		total    float64
+		wantErr  error
+	}{
+		{
+			name:     "sem descontos",
+			itens:    []SaleItem{{ProductID: 1, Quantidade: 3, Fator: 1}},
+			valores:  map[int64]float64{1: 10},
+			unitario: []float64{10},
+			totais:   []float64{30},
+			subtotal: 30,
+			total:    30,
+		},
+		{
+			name: "desconto por item e na venda",
+			itens: []SaleItem{
+				{ProductID: 1, Quantidade: 3, Fator: 1, Desconto: 0.97},
+				{ProductID: 2, Quantidade: 2, Fator: 1},
+			},
+			valores:  map[int64]float64{1: 19.99, 2: 5.5},
+			desconto: 5.5,
+			unitario: []float64{19.99, 5.5},
+			totais:   []float64{59, 11},
+			subtotal: 70,
+			total:    64.5,
+		},
+		{
+			name:     "fator de venda arredonda o valor unitário",
+			itens:    []SaleItem{{ProductID: 1, Quantidade: 2, Fator: 12}},
+			valores:  map[int64]float64{1: 0.333},
+			unitario: []float64{4},
+			totais:   []float64{8},
+			subtotal: 8,
+			total:    8,
+		},
+		{
+			name:     "quantidade fracionada arredonda o total",
+			itens:    []SaleItem{{ProductID: 1, Quantidade: 0.735, Fator: 1}},
+			valores:  map[int64]float64{1: 12.99},
+			unitario: []float64{12.99},
+			totais:   []float64{9.55},
+			subtotal: 9.55,
+			total:    9.55,
+		},
+		{
+			name:     "desconto igual ao valor do item",
+			itens:    []SaleItem{{ProductID: 1, Quantidade: 1, Fator: 1, Desconto: 7.5}},
+			valores:  map[int64]float64{1: 7.5},
+			unitario: []float64{7.5},
+			totais:   []float64{0},
+		},
+		{
+			name:    "desconto maior que o item",
+			itens:   []SaleItem{{ProductID: 1, Quantidade: 1, Fator: 1, Desconto: 7.51}},
+			valores: map[int64]float64{1: 7.5},
+			wantErr: ErrInvalidDiscount,
+		},
+		{
+			name:     "desconto maior que o subtotal",
+			itens:    []SaleItem{{ProductID: 1, Quantidade: 2, Fator: 1}},
+			valores:  map[int64]float64{1: 4},
+			desconto: 8.01,
+			wantErr:  ErrInvalidDiscount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sale := Sale{Itens: tt.itens, Desconto: tt.desconto}
+
+			err := sale.calculateTotals(tt.valores)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("calculateTotals() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			for i, item := range sale.Itens {
+				if item.ValorUnitario != tt.unitario[i] {
+					t.Errorf("Itens[%d].ValorUnitario = %v, want %v", i, item.ValorUnitario, tt.unitario[i])
+				}
+
+				if item.Total != tt.totais[i] {
+					t.Errorf("Itens[%d].Total = %v, want %v", i, item.Total, tt.totais[i])
+				}
+			}
+
+			if sale.Subtotal != tt.subtotal {
+				t.Errorf("Subtotal = %v, want %v", sale.Subtotal, tt.subtotal)
+			}
+
+			if sale.Total != tt.total {
+				t.Errorf("Total = %v, want %v", sale.Total, tt.total)
+			}
+		})
+	}
+}
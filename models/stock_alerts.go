@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	AlertPontoDePedido = "PONTO_DE_PEDIDO"
+	AlertEstoqueMinimo = "ESTOQUE_MINIMO"
+)
+
+type StockAlert struct {
+	ID                int64      `json:"id"`
+	ProductID         int64      `json:"product_id"`
+	ProductNome       string     `json:"product_nome"`
+	SKU               string     `json:"sku"`
+	EstabelecimentoID int64      `json:"estabelecimento_id"`
+	Tipo              string     `json:"tipo"`
+	Status            string     `json:"status"`
+	Estoque           float64    `json:"estoque"`
+	Limite            float64    `json:"limite"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ResolvedAt        *time.Time `json:"resolved_at"`
+}
+
+// alertThresholds associa cada tipo de alerta à condição que o dispara.
+var alertThresholds = []struct {
+	tipo      string
+	column    string
+	condition string
+}{
+	{AlertPontoDePedido, "ponto_de_pedido", "p.ponto_de_pedido > 0 AND p.estoque <= p.ponto_de_pedido"},
+	{AlertEstoqueMinimo, "estoque_minimo", "p.estoque_minimo > 0 AND p.estoque < p.estoque_minimo"},
+}
+
+// execer é atendido tanto por *sql.DB quanto por *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// checkStockAlerts abre um alerta para cada produto que cruzou um limite para
+// baixo e resolve os alertas abertos dos produtos que voltaram acima dele,
+// considerando apenas os produtos que atendem filter. Os argumentos do filtro
+// começam em $2.
+func checkStockAlerts(e execer, filter string, args ...interface{}) error {
+	for _, threshold := range alertThresholds {
+		queryArgs := append([]interface{}{threshold.tipo}, args...)
+
+		openQuery := `INSERT INTO stock_alerts(product_id, tipo, estoque, limite)
+		SELECT p.id, $1, p.estoque, p.` + threshold.column + `
+		FROM products p
+		WHERE p.deleted_at IS NULL AND p.tipo <> 'KIT' AND ` + threshold.condition + ` AND ` + filter + `
+		AND NOT EXISTS (
+			SELECT 1 FROM stock_alerts a
+			WHERE a.product_id = p.id AND a.tipo = $1 AND a.status = 'ABERTO'
+		)`
+
+		_, err := e.Exec(openQuery, queryArgs...)
+		if err != nil {
+			return err
+		}
+
+		resolveQuery := `UPDATE stock_alerts a
+		SET status = 'RESOLVIDO', resolved_at = NOW()
+		FROM products p
+		WHERE a.product_id = p.id AND a.tipo = $1 AND a.status = 'ABERTO'
+		AND NOT (` + threshold.condition + `) AND ` + filter
+
+		_, err = e.Exec(resolveQuery, queryArgs...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CheckStockAlerts confere os limites de todos os produtos.
+func CheckStockAlerts() error {
+	return checkStockAlerts(db.DB, "TRUE")
+}
+
+// checkProductStockAlerts confere os limites do produto dentro da transação
+// que alterou o seu estoque, para que um cruzamento que se desfaz antes da
+// próxima verificação periódica não passe sem alerta.
+func checkProductStockAlerts(tx *sql.Tx, productId int64) error {
+	return checkStockAlerts(tx, "p.id = $2", productId)
+}
+
+// StartStockAlertChecker executa CheckStockAlerts periodicamente, o que cobre
+// as mudanças de estoque mínimo e ponto de pedido. Deve ser chamado em uma
+// goroutine própria.
+func StartStockAlertChecker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := CheckStockAlerts()
+		if err != nil {
+			log.Println("Erro ao verificar alertas de estoque:", err)
+		}
+	}
+}
+
+func GetStockAlerts(role, userId, status string) ([]StockAlert, error) {
+	query := `SELECT a.id, a.product_id, p.nome, p.sku, p.estabelecimento_id, a.tipo, a.status, a.estoque, a.limite, a.created_at, a.resolved_at
+	FROM stock_alerts a
+	JOIN products p ON p.id = a.product_id
+	WHERE 1=1`
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, estabelecimentoId)
+		query += " AND p.estabelecimento_id = $1"
+	}
+
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND a.status = $%d", len(args))
+	}
+
+	query += " ORDER BY a.created_at DESC, a.id DESC"
+
+	rows, err := db.DB.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var alerts []StockAlert
+
+	for rows.Next() {
+		var alert StockAlert
+		err := rows.Scan(&alert.ID, &alert.ProductID, &alert.ProductNome, &alert.SKU, &alert.EstabelecimentoID, &alert.Tipo, &alert.Status, &alert.Estoque, &alert.Limite, &alert.CreatedAt, &alert.ResolvedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,330 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	MovementEntrada = "ENTRADA"
+	MovementSaida   = "SAIDA"
+	MovementAjuste  = "AJUSTE"
+)
+
+var (
+	ErrInsufficientStock = errors.New("estoque insuficiente para realizar a movimentação")
+	ErrInvalidQuantity   = errors.New("quantidade inválida para o tipo de movimentação")
+)
+
+type StockMovement struct {
+	ID               int64              `json:"id"`
+	ProductID        int64              `json:"product_id"`
+	Tipo             string             `json:"tipo" binding:"required,oneof=ENTRADA SAIDA AJUSTE"`
+	Quantidade       float64            `json:"quantidade" binding:"required"`
+	Motivo           string             `json:"motivo" binding:"required"`
+	Lote             string             `json:"lote,omitempty"`
+	Validade         string             `json:"validade,omitempty"`
+	Lotes            []LotConsumption   `json:"lotes,omitempty"`
+	LocationID       *int64             `json:"location_id,omitempty"`
+	Localizacoes     []MovementLocation `json:"localizacoes,omitempty"`
+	CustoUnitario    *float64           `json:"custo_unitario,omitempty" binding:"omitempty,gte=0"`
+	EstoqueAnterior  float64            `json:"estoque_anterior"`
+	EstoquePosterior float64            `json:"estoque_posterior"`
+	UserID           int64              `json:"user_id"`
+	KitMovementID    *int64             `json:"kit_movement_id,omitempty"`
+	Componentes      []StockMovement    `json:"componentes,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	// PermiteEstoqueNegativo deixa a saída ultrapassar o saldo, conforme a
+	// configuração do estabelecimento.
+	PermiteEstoqueNegativo bool `json:"-"`
+}
+
+func roundQuantity(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// delta retorna a variação que a movimentação aplica ao estoque. Entradas e
+// saídas usam quantidades positivas; ajustes aceitam valores com sinal.
+func (m *StockMovement) delta() (float64, error) {
+	switch m.Tipo {
+	case MovementEntrada:
+		if m.Quantidade <= 0 {
+			return 0, ErrInvalidQuantity
+		}
+		return m.Quantidade, nil
+	case MovementSaida:
+		if m.Quantidade <= 0 {
+			return 0, ErrInvalidQuantity
+		}
+		return -m.Quantidade, nil
+	case MovementAjuste:
+		if m.Quantidade == 0 {
+			return 0, ErrInvalidQuantity
+		}
+		return m.Quantidade, nil
+	default:
+		return 0, ErrInvalidQuantity
+	}
+}
+
+// Save registra a movimentação e atualiza products.estoque na mesma transação,
+// bloqueando com lockProducts a linha do produto, e nos kits a dos
+// componentes, para evitar atualizações concorrentes. A
+// quantidade está na unidade de estoque do produto. Kits ignoram o local, já
+// que cada componente sai de onde estiver. Saídas respeitam o estoque
+// reservado. Os alertas de estoque do produto são conferidos na mesma
+// transação.
+func (m *StockMovement) Save(tx *sql.Tx) error {
+	delta, err := m.delta()
+	if err != nil {
+		return err
+	}
+
+	// Só entradas carregam custo. Sem custo informado, a avaliação usa o custo
+	// vigente do produto.
+	if delta <= 0 {
+		m.CustoUnitario = nil
+	} else if m.CustoUnitario != nil {
+		custo := roundCost(*m.CustoUnitario)
+		m.CustoUnitario = &custo
+	}
+
+	err = lockProducts(tx, []int64{m.ProductID})
+	if err != nil {
+		return err
+	}
+
+	var estoqueAtual, reservado float64
+	var tipoProduto, unidade string
+	var estabelecimentoId int64
+	var hasVariants bool
+	err = tx.QueryRow(
+		"SELECT estoque, estoque_reservado, tipo, unidade, estabelecimento_id, EXISTS(SELECT 1 FROM products v WHERE v.parent_id = products.id) FROM products WHERE id = $1 FOR UPDATE",
+		m.ProductID,
+	).Scan(&estoqueAtual, &reservado, &tipoProduto, &unidade, &estabelecimentoId, &hasVariants)
+	if err != nil {
+		return err
+	}
+
+	err = checkUnitQuantity(unidade, delta)
+	if err != nil {
+		return err
+	}
+
+	if hasVariants {
+		return ErrParentProductStock
+	}
+
+	if tipoProduto == ProductKit {
+		return m.saveKit(tx)
+	}
+
+	novoEstoque := roundQuantity(estoqueAtual + delta)
+	if novoEstoque < 0 && !m.PermiteEstoqueNegativo {
+		return ErrInsufficientStock
+	}
+
+	// Saídas não podem consumir o que está reservado. Ajustes refletem o
+	// estoque físico e passam mesmo que o disponível fique negativo.
+	if m.Tipo == MovementSaida && novoEstoque < reservado && !m.PermiteEstoqueNegativo {
+		return ErrInsufficientStock
+	}
+
+	m.EstoqueAnterior = estoqueAtual
+	m.EstoquePosterior = novoEstoque
+
+	err = m.insert(tx)
+	if err != nil {
+		return err
+	}
+
+	err = m.applyLots(tx, delta)
+	if err != nil {
+		return err
+	}
+
+	err = m.applyLocations(tx, estabelecimentoId, delta)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE products SET estoque = $1, updated_at = $2 WHERE id = $3", novoEstoque, m.CreatedAt, m.ProductID)
+	if err != nil {
+		return err
+	}
+
+	return checkProductStockAlerts(tx, m.ProductID)
+}
+
+func (m *StockMovement) insert(tx *sql.Tx) error {
+	var userId interface{}
+	if m.UserID != 0 {
+		userId = m.UserID
+	}
+
+	// O estabelecimento e o método de custeio do momento ficam gravados na
+	// movimentação, para a avaliação do estoque em datas passadas.
+	query := `INSERT INTO stock_movements(product_id, tipo, quantidade, motivo, estoque_anterior, estoque_posterior, user_id, kit_movement_id, custo_unitario, estabelecimento_id, metodo_custeio)
+	SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, p.estabelecimento_id, e.metodo_custeio
+	FROM products p
+	JOIN estabelecimentos e ON e.id = p.estabelecimento_id
+	WHERE p.id = $1
+	RETURNING id, created_at`
+
+	return tx.QueryRow(
+		query,
+		m.ProductID, m.Tipo, m.Quantidade, m.Motivo, m.EstoqueAnterior, m.EstoquePosterior, userId, m.KitMovementID, m.CustoUnitario,
+	).Scan(&m.ID, &m.CreatedAt)
+}
+
+// restockFromMovement devolve ao estoque uma quantidade que saiu pela
+// movimentação informada, recompondo primeiro os lotes que ela consumiu. O que
+// não tinha lote volta como estoque sem lote. Saídas de kit são recompostas
+// componente a componente.
+func restockFromMovement(tx *sql.Tx, movementId, productId int64, quantidade float64, motivo string, userId int64) ([]StockMovement, error) {
+	var kitMovement bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM stock_movements WHERE kit_movement_id = $1)", movementId).Scan(&kitMovement)
+	if err != nil {
+		return nil, err
+	}
+
+	if kitMovement {
+		return restockKitFromMovement(tx, movementId, productId, quantidade, motivo, userId)
+	}
+
+	rows, err := tx.Query(`SELECT l.numero_lote, ml.quantidade
+	FROM stock_movement_lots ml
+	JOIN product_lots l ON l.id = ml.lot_id
+	WHERE ml.movement_id = $1
+	ORDER BY ml.id`, movementId)
+	if err != nil {
+		return nil, err
+	}
+
+	var lots []LotConsumption
+	for rows.Next() {
+		var lot LotConsumption
+		err := rows.Scan(&lot.NumeroLote, &lot.Quantidade)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+	rows.Close()
+
+	restante := roundQuantity(quantidade)
+	var movements []StockMovement
+
+	for _, lot := range lots {
+		if restante <= 0 {
+			break
+		}
+
+		devolvido := lot.Quantidade
+		if devolvido > restante {
+			devolvido = restante
+		}
+
+		movement := StockMovement{
+			ProductID:  productId,
+			Tipo:       MovementEntrada,
+			Quantidade: devolvido,
+			Motivo:     motivo,
+			Lote:       lot.NumeroLote,
+			UserID:     userId,
+		}
+
+		err = movement.Save(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		movements = append(movements, movement)
+		restante = roundQuantity(restante - devolvido)
+	}
+
+	if restante > 0 {
+		movement := StockMovement{
+			ProductID:  productId,
+			Tipo:       MovementEntrada,
+			Quantidade: restante,
+			Motivo:     motivo,
+			UserID:     userId,
+		}
+
+		err = movement.Save(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		movements = append(movements, movement)
+	}
+
+	return movements, nil
+}
+
+// applyLots reflete a movimentação nos lotes do produto: entradas com lote
+// informado alimentam o lote e saídas consomem os lotes por FEFO.
+func (m *StockMovement) applyLots(tx *sql.Tx, delta float64) error {
+	var err error
+
+	switch {
+	case delta > 0 && m.Lote != "":
+		var lot LotConsumption
+		lot, err = addToLot(tx, m.ProductID, m.Lote, m.Validade, delta)
+		m.Lotes = []LotConsumption{lot}
+	case delta < 0:
+		m.Lotes, err = consumeLots(tx, m.ProductID, m.Lote, -delta)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, lot := range m.Lotes {
+		_, err = tx.Exec(
+			"INSERT INTO stock_movement_lots(movement_id, lot_id, quantidade) VALUES($1, $2, $3)",
+			m.ID, lot.LotID, lot.Quantidade,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func GetProductMovements(productId int64) ([]StockMovement, error) {
+	query := `SELECT id, product_id, tipo, quantidade, motivo, estoque_anterior, estoque_posterior, COALESCE(user_id, 0), kit_movement_id, custo_unitario, created_at
+	FROM stock_movements
+	WHERE product_id = $1
+	ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.DB.Query(query, productId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var movements []StockMovement
+
+	for rows.Next() {
+		var movement StockMovement
+		err := rows.Scan(&movement.ID, &movement.ProductID, &movement.Tipo, &movement.Quantidade, &movement.Motivo, &movement.EstoqueAnterior, &movement.EstoquePosterior, &movement.UserID, &movement.KitMovementID, &movement.CustoUnitario, &movement.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		movements = append(movements, movement)
+	}
+
+	return movements, nil
+}
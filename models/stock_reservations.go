@@ -0,0 +1,316 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	ReservationAtiva      = "ATIVA"
+	ReservationConfirmada = "CONFIRMADA"
+	ReservationLiberada   = "LIBERADA"
+	ReservationExpirada   = "EXPIRADA"
+)
+
+// DefaultReservationTTL é usado quando STOCK_RESERVATION_TTL_MINUTES não está
+// definido ou é inválido.
+const DefaultReservationTTL = 15 * time.Minute
+
+var (
+	ErrInvalidReservationStatus = errors.New("a reserva não está em um status que permita essa operação")
+	ErrReservationExpired       = errors.New("a reserva expirou")
+	ErrKitReservation           = errors.New("kits não têm reservas; os componentes é que são reservados")
+)
+
+// StockReservation separa uma quantidade do estoque vendável sem baixá-la:
+// products.estoque não muda, mas o disponível diminui até a reserva ser
+// confirmada, liberada ou expirar. A quantidade está na unidade de estoque.
+type StockReservation struct {
+	ID           int64      `json:"id"`
+	ProductID    int64      `json:"product_id"`
+	ProductNome  string     `json:"product_nome,omitempty"`
+	Quantidade   float64    `json:"quantidade" binding:"required,gt=0"`
+	Referencia   string     `json:"referencia" binding:"max=100"`
+	TTLMinutos   int        `json:"ttl_minutos,omitempty" binding:"omitempty,gt=0,lte=1440"`
+	Status       string     `json:"status"`
+	ExpiraEm     time.Time  `json:"expira_em"`
+	MovementID   *int64     `json:"movement_id"`
+	UserID       int64      `json:"user_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	FinalizadaEm *time.Time `json:"finalizada_em"`
+}
+
+const reservationColumns = "r.id, r.product_id, p.nome, r.quantidade, r.referencia, r.status, r.expira_em, r.movement_id, COALESCE(r.user_id, 0), r.created_at, r.finalizada_em"
+
+func scanReservation(row rowScanner, r *StockReservation) error {
+	return row.Scan(&r.ID, &r.ProductID, &r.ProductNome, &r.Quantidade, &r.Referencia, &r.Status, &r.ExpiraEm, &r.MovementID, &r.UserID, &r.CreatedAt, &r.FinalizadaEm)
+}
+
+// ReservationTTL lê de STOCK_RESERVATION_TTL_MINUTES por quanto tempo uma
+// reserva segura o estoque quando a requisição não informa ttl_minutos.
+func ReservationTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("STOCK_RESERVATION_TTL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return DefaultReservationTTL
+	}
+
+	return time.Duration(minutes) * time.Minute
+}
+
+// Save cria a reserva com a linha do produto bloqueada, desde que o disponível
+// (estoque menos o já reservado) comporte a quantidade. Reservas nunca
+// deixam o disponível negativo, mesmo em estabelecimentos que permitem
+// estoque negativo.
+func (r *StockReservation) Save(tx *sql.Tx, role string, userId int64) error {
+	quantidade := roundQuantity(r.Quantidade)
+	if quantidade <= 0 {
+		return ErrInvalidQuantity
+	}
+	r.Quantidade = quantidade
+
+	var estoque, reservado float64
+	var tipo, unidade string
+	var estabelecimentoId int64
+	var hasVariants bool
+	err := tx.QueryRow(
+		`SELECT nome, estoque, estoque_reservado, tipo, unidade, estabelecimento_id, EXISTS(SELECT 1 FROM products v WHERE v.parent_id = products.id)
+		FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		r.ProductID,
+	).Scan(&r.ProductNome, &estoque, &reservado, &tipo, &unidade, &estabelecimentoId, &hasVariants)
+	if err != nil {
+		return err
+	}
+
+	err = CheckEstablishmentAccess(role, strconv.FormatInt(userId, 10), estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	if hasVariants {
+		return ErrParentProductStock
+	}
+
+	if tipo == ProductKit {
+		return ErrKitReservation
+	}
+
+	err = checkUnitQuantity(unidade, quantidade)
+	if err != nil {
+		return err
+	}
+
+	if roundQuantity(estoque-reservado) < quantidade {
+		return ErrInsufficientStock
+	}
+
+	ttl := ReservationTTL()
+	if r.TTLMinutos > 0 {
+		ttl = time.Duration(r.TTLMinutos) * time.Minute
+	}
+
+	r.Status = ReservationAtiva
+	r.UserID = userId
+
+	query := `INSERT INTO stock_reservations(product_id, quantidade, referencia, status, expira_em, user_id)
+	VALUES($1, $2, $3, $4, NOW() + make_interval(secs => $5), $6)
+	RETURNING id, expira_em, created_at`
+
+	err = tx.QueryRow(query, r.ProductID, r.Quantidade, r.Referencia, r.Status, ttl.Seconds(), userId).Scan(&r.ID, &r.ExpiraEm, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE products SET estoque_reservado = estoque_reservado + $1 WHERE id = $2", r.Quantidade, r.ProductID)
+
+	return err
+}
+
+func GetProductReservations(productId int64, status string) ([]StockReservation, error) {
+	query := "SELECT " + reservationColumns + ` FROM stock_reservations r
+	JOIN products p ON p.id = r.product_id
+	WHERE r.product_id = $1`
+	args := []interface{}{productId}
+
+	if status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND r.status = $%d", len(args))
+	}
+
+	query += " ORDER BY r.created_at DESC, r.id DESC"
+
+	rows, err := db.DB.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var reservations []StockReservation
+
+	for rows.Next() {
+		var reservation StockReservation
+		err := scanReservation(rows, &reservation)
+
+		if err != nil {
+			return nil, err
+		}
+
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, nil
+}
+
+func GetStockReservation(id int64, role, userId string) (*StockReservation, error) {
+	query := "SELECT " + reservationColumns + `, p.estabelecimento_id FROM stock_reservations r
+	JOIN products p ON p.id = r.product_id
+	WHERE r.id = $1`
+
+	var reservation StockReservation
+	var estabelecimentoId int64
+	err := db.DB.QueryRow(query, id).Scan(
+		&reservation.ID, &reservation.ProductID, &reservation.ProductNome, &reservation.Quantidade, &reservation.Referencia, &reservation.Status,
+		&reservation.ExpiraEm, &reservation.MovementID, &reservation.UserID, &reservation.CreatedAt, &reservation.FinalizadaEm, &estabelecimentoId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, estabelecimentoId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// lockActive bloqueia a reserva antes do produto, na mesma ordem usada por
+// ExpireReservations, e confere que ela ainda está ativa.
+func (r *StockReservation) lockActive(tx *sql.Tx) error {
+	var expirada bool
+	err := tx.QueryRow(
+		"SELECT product_id, quantidade, referencia, status, expira_em, expira_em <= NOW() FROM stock_reservations WHERE id = $1 FOR UPDATE",
+		r.ID,
+	).Scan(&r.ProductID, &r.Quantidade, &r.Referencia, &r.Status, &r.ExpiraEm, &expirada)
+	if err != nil {
+		return err
+	}
+
+	if r.Status != ReservationAtiva {
+		return ErrInvalidReservationStatus
+	}
+
+	if expirada {
+		return ErrReservationExpired
+	}
+
+	return nil
+}
+
+// unreserve tira a quantidade da reserva do estoque reservado do produto.
+func (r *StockReservation) unreserve(tx *sql.Tx) error {
+	_, err := tx.Exec("UPDATE products SET estoque_reservado = GREATEST(estoque_reservado - $1, 0) WHERE id = $2", r.Quantidade, r.ProductID)
+	return err
+}
+
+func (r *StockReservation) close(tx *sql.Tx, status string) error {
+	r.Status = status
+
+	return tx.QueryRow(
+		"UPDATE stock_reservations SET status = $1, movement_id = $2, finalizada_em = NOW() WHERE id = $3 RETURNING finalizada_em",
+		r.Status, r.MovementID, r.ID,
+	).Scan(&r.FinalizadaEm)
+}
+
+// Confirm baixa a quantidade reservada do estoque com uma saída. A reserva sai
+// do estoque reservado antes da saída para que ela possa consumir o que
+// estava separado.
+func (r *StockReservation) Confirm(tx *sql.Tx, userId int64) error {
+	err := r.lockActive(tx)
+	if err != nil {
+		return err
+	}
+
+	err = r.unreserve(tx)
+	if err != nil {
+		return err
+	}
+
+	motivo := fmt.Sprintf("Confirmação da reserva #%d", r.ID)
+	if r.Referencia != "" {
+		motivo = fmt.Sprintf("%s (%s)", motivo, r.Referencia)
+	}
+
+	movement := StockMovement{
+		ProductID:  r.ProductID,
+		Tipo:       MovementSaida,
+		Quantidade: r.Quantidade,
+		Motivo:     motivo,
+		UserID:     userId,
+	}
+
+	err = movement.Save(tx)
+	if err != nil {
+		return err
+	}
+
+	r.MovementID = &movement.ID
+
+	return r.close(tx, ReservationConfirmada)
+}
+
+// Release devolve a quantidade reservada ao disponível sem movimentar o
+// estoque. Reservas vencidas que o sweeper ainda não expirou também podem ser
+// liberadas.
+func (r *StockReservation) Release(tx *sql.Tx) error {
+	err := r.lockActive(tx)
+	if err != nil && !errors.Is(err, ErrReservationExpired) {
+		return err
+	}
+
+	err = r.unreserve(tx)
+	if err != nil {
+		return err
+	}
+
+	return r.close(tx, ReservationLiberada)
+}
+
+// ExpireReservations encerra as reservas ativas cujo prazo passou e devolve as
+// quantidades ao disponível dos produtos.
+func ExpireReservations() error {
+	query := `WITH expiradas AS (
+		UPDATE stock_reservations SET status = $1, finalizada_em = NOW()
+		WHERE status = $2 AND expira_em <= NOW()
+		RETURNING product_id, quantidade
+	)
+	UPDATE products p
+	SET estoque_reservado = GREATEST(p.estoque_reservado - e.total, 0)
+	FROM (SELECT product_id, SUM(quantidade) AS total FROM expiradas GROUP BY product_id) e
+	WHERE p.id = e.product_id`
+
+	_, err := db.DB.Exec(query, ReservationExpirada, ReservationAtiva)
+	return err
+}
+
+// StartReservationSweeper executa ExpireReservations periodicamente. Deve ser
+// chamado em uma goroutine própria.
+func StartReservationSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := ExpireReservations()
+		if err != nil {
+			log.Println("Erro ao expirar reservas de estoque:", err)
+		}
+	}
+}
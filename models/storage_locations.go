@@ -0,0 +1,667 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	LocationArea       = "AREA"
+	LocationCorredor   = "CORREDOR"
+	LocationPrateleira = "PRATELEIRA"
+	LocationPosicao    = "POSICAO"
+)
+
+// locationLevels ordena os tipos de local: um local só pode ficar dentro de
+// outro de nível menor.
+var locationLevels = map[string]int{
+	LocationArea:       1,
+	LocationCorredor:   2,
+	LocationPrateleira: 3,
+	LocationPosicao:    4,
+}
+
+var (
+	ErrInvalidLocation           = errors.New("local de armazenagem inválido para o estabelecimento")
+	ErrInvalidParentLocation     = errors.New("local pai inválido")
+	ErrLocationInUse             = errors.New("o local possui sublocais ou produtos armazenados")
+	ErrInsufficientLocationStock = errors.New("quantidade insuficiente no local de armazenagem")
+	ErrSameLocation              = errors.New("origem e destino devem ser locais diferentes")
+	ErrReservedLocationCode      = errors.New("o código PADRAO é reservado ao local padrão")
+)
+
+// DefaultLocationCode é o código do local padrão, criado junto com o
+// estabelecimento. Nenhum outro local pode usá-lo.
+const DefaultLocationCode = "PADRAO"
+
+type StorageLocation struct {
+	ID                int64     `json:"id"`
+	EstabelecimentoID int64     `json:"estabelecimento_id" binding:"required"`
+	ParentID          *int64    `json:"parent_id"`
+	Tipo              string    `json:"tipo" binding:"required,oneof=AREA CORREDOR PRATELEIRA POSICAO"`
+	Codigo            string    `json:"codigo" binding:"required"`
+	Descricao         string    `json:"descricao"`
+	Padrao            bool      `json:"padrao"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type StorageLocationFilter struct {
+	ParentID string
+	Tipo     string
+	Codigo   string
+}
+
+// ProductLocation é o saldo de um produto em um local.
+type ProductLocation struct {
+	ProductID   int64   `json:"product_id"`
+	ProductNome string  `json:"product_nome,omitempty"`
+	SKU         string  `json:"sku,omitempty"`
+	LocationID  int64   `json:"location_id"`
+	Codigo      string  `json:"codigo"`
+	Tipo        string  `json:"tipo"`
+	Quantidade  float64 `json:"quantidade"`
+}
+
+// MovementLocation registra quanto uma movimentação alterou em um local.
+type MovementLocation struct {
+	LocationID int64   `json:"location_id"`
+	Quantidade float64 `json:"quantidade"`
+}
+
+// LocationMove tira uma quantidade de um local e a coloca em outro do mesmo
+// estabelecimento, sem alterar o estoque total. Sem origem, é um endereçamento
+// a partir do local padrão.
+type LocationMove struct {
+	ID                int64     `json:"id"`
+	ProductID         int64     `json:"product_id"`
+	OrigemLocationID  *int64    `json:"origem_location_id"`
+	DestinoLocationID int64     `json:"destino_location_id" binding:"required"`
+	Quantidade        float64   `json:"quantidade" binding:"required,gt=0"`
+	Motivo            string    `json:"motivo"`
+	UserID            int64     `json:"user_id"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+const storageLocationColumns = "id, estabelecimento_id, parent_id, tipo, codigo, descricao, padrao, created_at, updated_at"
+
+var StorageLocationSortColumns = map[string]string{
+	"id":         "id",
+	"codigo":     "codigo",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+func scanStorageLocation(row rowScanner, l *StorageLocation) error {
+	return row.Scan(&l.ID, &l.EstabelecimentoID, &l.ParentID, &l.Tipo, &l.Codigo, &l.Descricao, &l.Padrao, &l.CreatedAt, &l.UpdatedAt)
+}
+
+// createDefaultLocation cria o local padrão de um estabelecimento recém
+// cadastrado. Os estabelecimentos anteriores aos locais o recebem na migração.
+func createDefaultLocation(tx *sql.Tx, estabelecimentoId int64) error {
+	_, err := tx.Exec(
+		`INSERT INTO storage_locations(estabelecimento_id, tipo, codigo, descricao, padrao)
+		VALUES($1, $2, $3, 'Local padrão', TRUE)`,
+		estabelecimentoId, LocationArea, DefaultLocationCode,
+	)
+
+	return err
+}
+
+// defaultLocationID devolve o local padrão do estabelecimento.
+func defaultLocationID(tx *sql.Tx, estabelecimentoId int64) (int64, error) {
+	var locationId int64
+	err := tx.QueryRow("SELECT id FROM storage_locations WHERE estabelecimento_id = $1 AND padrao", estabelecimentoId).Scan(&locationId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidLocation
+	}
+
+	return locationId, err
+}
+
+// checkLocation garante que o local existe no estabelecimento informado.
+func checkLocation(tx *sql.Tx, locationId, estabelecimentoId int64) error {
+	var locationEstabId int64
+	err := tx.QueryRow("SELECT estabelecimento_id FROM storage_locations WHERE id = $1", locationId).Scan(&locationEstabId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidLocation
+	}
+	if err != nil {
+		return err
+	}
+
+	if locationEstabId != estabelecimentoId {
+		return ErrInvalidLocation
+	}
+
+	return nil
+}
+
+// addToLocation soma delta ao saldo do produto no local.
+func addToLocation(tx *sql.Tx, productId, locationId int64, delta float64) error {
+	_, err := tx.Exec(
+		`INSERT INTO product_locations(product_id, location_id, quantidade) VALUES($1, $2, $3)
+		ON CONFLICT (product_id, location_id) DO UPDATE SET quantidade = product_locations.quantidade + EXCLUDED.quantidade`,
+		productId, locationId, roundQuantity(delta),
+	)
+
+	return err
+}
+
+// takeFromLocation retira a quantidade do local, que precisa ter saldo
+// suficiente a menos que o estoque negativo seja permitido.
+func takeFromLocation(tx *sql.Tx, productId, locationId int64, quantidade float64, permiteNegativo bool) error {
+	var saldo float64
+	err := tx.QueryRow(
+		"SELECT quantidade FROM product_locations WHERE product_id = $1 AND location_id = $2 FOR UPDATE",
+		productId, locationId,
+	).Scan(&saldo)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if roundQuantity(saldo-quantidade) < 0 && !permiteNegativo {
+		return ErrInsufficientLocationStock
+	}
+
+	return addToLocation(tx, productId, locationId, -quantidade)
+}
+
+// applyLocations reflete a movimentação nos locais do estabelecimento.
+// Entradas vão para o local informado ou para o padrão. Saídas sem local
+// consomem primeiro o local padrão e depois os demais, em ordem de id; o que
+// faltar com estoque negativo permitido fica negativo no padrão.
+func (m *StockMovement) applyLocations(tx *sql.Tx, estabelecimentoId int64, delta float64) error {
+	var applied []MovementLocation
+
+	switch {
+	case m.LocationID != nil:
+		err := checkLocation(tx, *m.LocationID, estabelecimentoId)
+		if err != nil {
+			return err
+		}
+
+		if delta > 0 {
+			err = addToLocation(tx, m.ProductID, *m.LocationID, delta)
+		} else {
+			err = takeFromLocation(tx, m.ProductID, *m.LocationID, -delta, m.PermiteEstoqueNegativo)
+		}
+		if err != nil {
+			return err
+		}
+
+		applied = append(applied, MovementLocation{LocationID: *m.LocationID, Quantidade: delta})
+	case delta > 0:
+		locationId, err := defaultLocationID(tx, estabelecimentoId)
+		if err != nil {
+			return err
+		}
+
+		err = addToLocation(tx, m.ProductID, locationId, delta)
+		if err != nil {
+			return err
+		}
+
+		applied = append(applied, MovementLocation{LocationID: locationId, Quantidade: delta})
+	default:
+		rows, err := tx.Query(`SELECT pl.location_id, pl.quantidade
+		FROM product_locations pl
+		JOIN storage_locations l ON l.id = pl.location_id
+		WHERE pl.product_id = $1 AND l.estabelecimento_id = $2 AND pl.quantidade > 0
+		ORDER BY l.padrao DESC, l.id
+		FOR UPDATE OF pl`, m.ProductID, estabelecimentoId)
+		if err != nil {
+			return err
+		}
+
+		var available []MovementLocation
+		for rows.Next() {
+			var location MovementLocation
+			err := rows.Scan(&location.LocationID, &location.Quantidade)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			available = append(available, location)
+		}
+		rows.Close()
+
+		restante := roundQuantity(-delta)
+		for _, location := range available {
+			if restante <= 0 {
+				break
+			}
+
+			retirado := location.Quantidade
+			if retirado > restante {
+				retirado = restante
+			}
+
+			err = addToLocation(tx, m.ProductID, location.LocationID, -retirado)
+			if err != nil {
+				return err
+			}
+
+			applied = append(applied, MovementLocation{LocationID: location.LocationID, Quantidade: -retirado})
+			restante = roundQuantity(restante - retirado)
+		}
+
+		if restante > 0 {
+			locationId, err := defaultLocationID(tx, estabelecimentoId)
+			if err != nil {
+				return err
+			}
+
+			err = addToLocation(tx, m.ProductID, locationId, -restante)
+			if err != nil {
+				return err
+			}
+
+			applied = append(applied, MovementLocation{LocationID: locationId, Quantidade: -restante})
+		}
+	}
+
+	for _, location := range applied {
+		_, err := tx.Exec(
+			"INSERT INTO stock_movement_locations(movement_id, location_id, quantidade) VALUES($1, $2, $3)",
+			m.ID, location.LocationID, location.Quantidade,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	m.Localizacoes = applied
+
+	return nil
+}
+
+// relocateProduct leva todo o saldo do produto para o local padrão do
+// estabelecimento, usado quando o produto muda de estabelecimento.
+func relocateProduct(tx *sql.Tx, productId, estabelecimentoId int64) error {
+	var total float64
+	err := tx.QueryRow("SELECT COALESCE(SUM(quantidade), 0) FROM product_locations WHERE product_id = $1", productId).Scan(&total)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM product_locations WHERE product_id = $1", productId)
+	if err != nil {
+		return err
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	locationId, err := defaultLocationID(tx, estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	return addToLocation(tx, productId, locationId, total)
+}
+
+func getProductLocations(q queryer, productId int64) ([]ProductLocation, error) {
+	rows, err := q.Query(`SELECT pl.product_id, pl.location_id, l.codigo, l.tipo, pl.quantidade
+	FROM product_locations pl
+	JOIN storage_locations l ON l.id = pl.location_id
+	WHERE pl.product_id = $1 AND pl.quantidade <> 0
+	ORDER BY l.padrao DESC, l.codigo`, productId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	locations := []ProductLocation{}
+
+	for rows.Next() {
+		var location ProductLocation
+		err := rows.Scan(&location.ProductID, &location.LocationID, &location.Codigo, &location.Tipo, &location.Quantidade)
+
+		if err != nil {
+			return nil, err
+		}
+
+		locations = append(locations, location)
+	}
+
+	return locations, nil
+}
+
+// LoadLocations carrega o saldo do produto em cada local de armazenagem.
+func (p *Product) LoadLocations() error {
+	var err error
+	p.Localizacoes, err = getProductLocations(db.DB, p.ID)
+	return err
+}
+
+// validateParent confere que o local pai é do mesmo estabelecimento e de um
+// nível acima, o que também impede ciclos.
+func (l *StorageLocation) validateParent(tx *sql.Tx) error {
+	if l.ParentID == nil {
+		return nil
+	}
+
+	var parentEstabId int64
+	var parentTipo string
+	err := tx.QueryRow("SELECT estabelecimento_id, tipo FROM storage_locations WHERE id = $1", *l.ParentID).Scan(&parentEstabId, &parentTipo)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidParentLocation
+	}
+	if err != nil {
+		return err
+	}
+
+	if parentEstabId != l.EstabelecimentoID || locationLevels[parentTipo] >= locationLevels[l.Tipo] {
+		return ErrInvalidParentLocation
+	}
+
+	return nil
+}
+
+func (l *StorageLocation) Save(tx *sql.Tx, role, userId string) error {
+	err := CheckEstablishmentAccess(role, userId, l.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	l.Codigo = strings.ToUpper(strings.TrimSpace(l.Codigo))
+	l.Padrao = false
+
+	if l.Codigo == DefaultLocationCode {
+		return ErrReservedLocationCode
+	}
+
+	err = l.validateParent(tx)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO storage_locations(estabelecimento_id, parent_id, tipo, codigo, descricao)
+	VALUES($1, $2, $3, $4, $5)
+	RETURNING id, created_at, updated_at`
+
+	return tx.QueryRow(query, l.EstabelecimentoID, l.ParentID, l.Tipo, l.Codigo, l.Descricao).Scan(&l.ID, &l.CreatedAt, &l.UpdatedAt)
+}
+
+func GetAllStorageLocations(role, userId string, filter StorageLocationFilter, params ListParams) ([]StorageLocation, ListResult, error) {
+	baseQuery := "SELECT " + storageLocationColumns + " FROM storage_locations WHERE 1=1"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	if filter.ParentID == "null" {
+		baseQuery += " AND parent_id IS NULL"
+	} else if parentId, err := strconv.ParseInt(filter.ParentID, 10, 64); err == nil {
+		args = append(args, parentId)
+		baseQuery += fmt.Sprintf(" AND parent_id = $%d", len(args))
+	}
+	if filter.Tipo != "" {
+		args = append(args, filter.Tipo)
+		baseQuery += fmt.Sprintf(" AND tipo = $%d", len(args))
+	}
+	if filter.Codigo != "" {
+		args = append(args, "%"+filter.Codigo+"%")
+		baseQuery += fmt.Sprintf(" AND codigo ILIKE $%d", len(args))
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var locations []StorageLocation
+
+	for rows.Next() {
+		var location StorageLocation
+		err := scanStorageLocation(rows, &location)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		locations = append(locations, location)
+	}
+
+	fetched := len(locations)
+	if fetched > params.PerPage {
+		locations = locations[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(locations) > 0 {
+		lastID = locations[len(locations)-1].ID
+	}
+
+	return locations, params.result(total, fetched, lastID), nil
+}
+
+func GetStorageLocation(id int64, role, userId string) (*StorageLocation, error) {
+	row := db.DB.QueryRow("SELECT "+storageLocationColumns+" FROM storage_locations WHERE id = $1", id)
+
+	var location StorageLocation
+
+	err := scanStorageLocation(row, &location)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, location.EstabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &location, nil
+}
+
+// GetLocationProducts lista os produtos com saldo no local.
+func GetLocationProducts(locationId int64) ([]ProductLocation, error) {
+	rows, err := db.DB.Query(`SELECT pl.product_id, p.nome, p.sku, pl.location_id, l.codigo, l.tipo, pl.quantidade
+	FROM product_locations pl
+	JOIN products p ON p.id = pl.product_id
+	JOIN storage_locations l ON l.id = pl.location_id
+	WHERE pl.location_id = $1 AND pl.quantidade <> 0 AND p.deleted_at IS NULL
+	ORDER BY p.nome, p.id`, locationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	products := []ProductLocation{}
+
+	for rows.Next() {
+		var product ProductLocation
+		err := rows.Scan(&product.ProductID, &product.ProductNome, &product.SKU, &product.LocationID, &product.Codigo, &product.Tipo, &product.Quantidade)
+
+		if err != nil {
+			return nil, err
+		}
+
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+// Update altera código, descrição e local pai. O tipo e o estabelecimento não
+// mudam, e o local padrão mantém o código reservado.
+func (l *StorageLocation) Update(tx *sql.Tx) error {
+	l.Codigo = strings.ToUpper(strings.TrimSpace(l.Codigo))
+
+	if (l.Codigo == DefaultLocationCode) != l.Padrao {
+		return ErrReservedLocationCode
+	}
+
+	err := l.validateParent(tx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE storage_locations
+	SET parent_id = $1, codigo = $2, descricao = $3, updated_at = $4
+	WHERE id = $5`
+
+	_, err = tx.Exec(query, l.ParentID, l.Codigo, l.Descricao, l.UpdatedAt, l.ID)
+
+	return err
+}
+
+// Delete remove um local vazio e sem sublocais. O local padrão não pode ser
+// removido.
+func (l *StorageLocation) Delete(tx *sql.Tx) error {
+	var inUse bool
+	err := tx.QueryRow(`SELECT padrao
+		OR EXISTS(SELECT 1 FROM storage_locations WHERE parent_id = $1)
+		OR EXISTS(SELECT 1 FROM product_locations WHERE location_id = $1 AND quantidade <> 0)
+	FROM storage_locations WHERE id = $1 FOR UPDATE`, l.ID).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+
+	if inUse {
+		return ErrLocationInUse
+	}
+
+	_, err = tx.Exec("DELETE FROM storage_locations WHERE id = $1", l.ID)
+
+	return err
+}
+
+// Save move o saldo entre dois locais com o produto bloqueado, como nas
+// movimentações de estoque.
+func (mv *LocationMove) Save(tx *sql.Tx, role string, userId int64) error {
+	err := lockProducts(tx, []int64{mv.ProductID})
+	if err != nil {
+		return err
+	}
+
+	var estabelecimentoId int64
+	var unidade string
+	err = tx.QueryRow(
+		"SELECT estabelecimento_id, unidade FROM products WHERE id = $1 AND deleted_at IS NULL",
+		mv.ProductID,
+	).Scan(&estabelecimentoId, &unidade)
+	if err != nil {
+		return err
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	mv.Quantidade = roundQuantity(mv.Quantidade)
+	if mv.Quantidade <= 0 {
+		return ErrInvalidQuantity
+	}
+
+	err = checkUnitQuantity(unidade, mv.Quantidade)
+	if err != nil {
+		return err
+	}
+
+	if mv.OrigemLocationID == nil {
+		origemId, err := defaultLocationID(tx, estabelecimentoId)
+		if err != nil {
+			return err
+		}
+		mv.OrigemLocationID = &origemId
+	}
+
+	if *mv.OrigemLocationID == mv.DestinoLocationID {
+		return ErrSameLocation
+	}
+
+	err = checkLocation(tx, *mv.OrigemLocationID, estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	err = checkLocation(tx, mv.DestinoLocationID, estabelecimentoId)
+	if err != nil {
+		return err
+	}
+
+	err = takeFromLocation(tx, mv.ProductID, *mv.OrigemLocationID, mv.Quantidade, false)
+	if err != nil {
+		return err
+	}
+
+	err = addToLocation(tx, mv.ProductID, mv.DestinoLocationID, mv.Quantidade)
+	if err != nil {
+		return err
+	}
+
+	mv.UserID = userId
+
+	query := `INSERT INTO location_moves(product_id, origem_location_id, destino_location_id, quantidade, motivo, user_id)
+	VALUES($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+
+	return tx.QueryRow(
+		query,
+		mv.ProductID, mv.OrigemLocationID, mv.DestinoLocationID, mv.Quantidade, mv.Motivo, userId,
+	).Scan(&mv.ID, &mv.CreatedAt)
+}
+
+func GetProductLocationMoves(productId int64) ([]LocationMove, error) {
+	rows, err := db.DB.Query(`SELECT id, product_id, origem_location_id, COALESCE(destino_location_id, 0), quantidade, motivo, COALESCE(user_id, 0), created_at
+	FROM location_moves
+	WHERE product_id = $1
+	ORDER BY created_at DESC, id DESC`, productId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	moves := []LocationMove{}
+
+	for rows.Next() {
+		var move LocationMove
+		err := rows.Scan(&move.ID, &move.ProductID, &move.OrigemLocationID, &move.DestinoLocationID, &move.Quantidade, &move.Motivo, &move.UserID, &move.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		moves = append(moves, move)
+	}
+
+	return moves, nil
+}
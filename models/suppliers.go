@@ -0,0 +1,311 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+var ErrSupplierEstablishmentMismatch = errors.New("produto e fornecedor pertencem a estabelecimentos diferentes")
+
+type Supplier struct {
+	ID                int64     `json:"id"`
+	RazaoSocial       string    `json:"razao_social" binding:"required"`
+	NomeFantasia      string    `json:"nome_fantasia"`
+	CPFCNPJ           string    `json:"cpf_cnpj" binding:"required"`
+	Contato           string    `json:"contato"`
+	Email             string    `json:"email" binding:"omitempty,email"`
+	Telefone          string    `json:"telefone"`
+	EnderecoID        int64     `json:"endereco_id"`
+	Endereco          Address   `json:"endereco" binding:"required"`
+	EstabelecimentoID int64     `json:"estabelecimento_id" binding:"required"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type SupplierFilter struct {
+	RazaoSocial string
+	CPFCNPJ     string
+}
+
+// ProductSupplier é o vínculo entre um produto e um fornecedor, com o código
+// que o fornecedor usa para o produto e o custo da última compra.
+type ProductSupplier struct {
+	ProductID         int64     `json:"product_id"`
+	SupplierID        int64     `json:"supplier_id"`
+	ProductNome       string    `json:"product_nome,omitempty"`
+	SupplierNome      string    `json:"supplier_nome,omitempty"`
+	CodigoFornecedor  string    `json:"codigo_fornecedor"`
+	UltimoCusto       *float64  `json:"ultimo_custo" binding:"omitempty,gte=0"`
+	EstabelecimentoID int64     `json:"-"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+const supplierColumns = `s.id, s.razao_social, s.nome_fantasia, s.cpf_cnpj, s.contato, s.email, s.telefone, s.endereco_id,
+	s.estabelecimento_id, s.created_at, s.updated_at,
+	a.logradouro, a.complemento, a.numero, a.bairro, a.cidade, a.uf, a.cep`
+
+var SupplierSortColumns = map[string]string{
+	"id":           "s.id",
+	"razao_social": "s.razao_social",
+	"cpf_cnpj":     "s.cpf_cnpj",
+	"created_at":   "s.created_at",
+	"updated_at":   "s.updated_at",
+}
+
+func scanSupplier(row rowScanner, s *Supplier) error {
+	return row.Scan(
+		&s.ID, &s.RazaoSocial, &s.NomeFantasia, &s.CPFCNPJ, &s.Contato, &s.Email, &s.Telefone, &s.EnderecoID,
+		&s.EstabelecimentoID, &s.CreatedAt, &s.UpdatedAt,
+		&s.Endereco.Logradouro, &s.Endereco.Complemento, &s.Endereco.Numero, &s.Endereco.Bairro, &s.Endereco.Cidade, &s.Endereco.UF, &s.Endereco.CEP,
+	)
+}
+
+// Save grava o endereço e o fornecedor. O CPF/CNPJ já deve estar normalizado.
+func (s *Supplier) Save(tx *sql.Tx, role, userId string) error {
+	err := CheckEstablishmentAccess(role, userId, s.EstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	err = s.Endereco.Save(tx)
+	if err != nil {
+		return err
+	}
+
+	s.EnderecoID = s.Endereco.ID
+
+	query := `INSERT INTO suppliers(razao_social, nome_fantasia, cpf_cnpj, contato, email, telefone, endereco_id, estabelecimento_id)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, updated_at`
+
+	return tx.QueryRow(
+		query,
+		s.RazaoSocial, s.NomeFantasia, s.CPFCNPJ, s.Contato, s.Email, s.Telefone, s.EnderecoID, s.EstabelecimentoID,
+	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+}
+
+func GetAllSuppliers(role, userId string, filter SupplierFilter, params ListParams) ([]Supplier, ListResult, error) {
+	baseQuery := "SELECT " + supplierColumns + ` FROM suppliers s
+	JOIN enderecos a ON a.id = s.endereco_id
+	WHERE 1=1`
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND s.estabelecimento_id = $%d", len(args))
+	}
+
+	if filter.RazaoSocial != "" {
+		args = append(args, "%"+filter.RazaoSocial+"%")
+		baseQuery += fmt.Sprintf(" AND (s.razao_social ILIKE $%d OR s.nome_fantasia ILIKE $%d)", len(args), len(args))
+	}
+	if filter.CPFCNPJ != "" {
+		args = append(args, filter.CPFCNPJ)
+		baseQuery += fmt.Sprintf(" AND s.cpf_cnpj = $%d", len(args))
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "s.id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
+	}
+
+	defer rows.Close()
+
+	var suppliers []Supplier
+
+	for rows.Next() {
+		var supplier Supplier
+		err := scanSupplier(rows, &supplier)
+
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		suppliers = append(suppliers, supplier)
+	}
+
+	fetched := len(suppliers)
+	if fetched > params.PerPage {
+		suppliers = suppliers[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(suppliers) > 0 {
+		lastID = suppliers[len(suppliers)-1].ID
+	}
+
+	return suppliers, params.result(total, fetched, lastID), nil
+}
+
+func GetSupplier(id int64, role, userId string) (*Supplier, error) {
+	query := "SELECT " + supplierColumns + ` FROM suppliers s
+	JOIN enderecos a ON a.id = s.endereco_id
+	WHERE s.id = $1`
+
+	var supplier Supplier
+
+	err := scanSupplier(db.DB.QueryRow(query, id), &supplier)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = CheckEstablishmentAccess(role, userId, supplier.EstabelecimentoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &supplier, nil
+}
+
+func (s *Supplier) Update(tx *sql.Tx) error {
+	s.Endereco.ID = s.EnderecoID
+	s.Endereco.UpdatedAt = s.UpdatedAt
+
+	err := s.Endereco.Update(tx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE suppliers
+	SET razao_social = $1, nome_fantasia = $2, cpf_cnpj = $3, contato = $4, email = $5, telefone = $6, updated_at = $7
+	WHERE id = $8`
+
+	_, err = tx.Exec(query, s.RazaoSocial, s.NomeFantasia, s.CPFCNPJ, s.Contato, s.Email, s.Telefone, s.UpdatedAt, s.ID)
+
+	return err
+}
+
+// Delete remove o fornecedor e seu endereço. Os vínculos com produtos são
+// removidos em cascata.
+func (s *Supplier) Delete(tx *sql.Tx) error {
+	_, err := tx.Exec("DELETE FROM suppliers WHERE id = $1", s.ID)
+	if err != nil {
+		return err
+	}
+
+	address := Address{ID: s.EnderecoID}
+
+	return address.Delete(tx)
+}
+
+const productSupplierColumns = `ps.product_id, ps.supplier_id, p.nome, s.razao_social, ps.codigo_fornecedor, ps.ultimo_custo,
+	s.estabelecimento_id, ps.created_at, ps.updated_at`
+
+func scanProductSupplier(row rowScanner, ps *ProductSupplier) error {
+	return row.Scan(
+		&ps.ProductID, &ps.SupplierID, &ps.ProductNome, &ps.SupplierNome, &ps.CodigoFornecedor, &ps.UltimoCusto,
+		&ps.EstabelecimentoID, &ps.CreatedAt, &ps.UpdatedAt,
+	)
+}
+
+func getProductSuppliers(where string, id int64) ([]ProductSupplier, error) {
+	query := "SELECT " + productSupplierColumns + ` FROM product_suppliers ps
+	JOIN products p ON p.id = ps.product_id
+	JOIN suppliers s ON s.id = ps.supplier_id
+	WHERE ` + where + ` = $1
+	ORDER BY ps.product_id, ps.supplier_id`
+
+	rows, err := db.DB.Query(query, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var links []ProductSupplier
+
+	for rows.Next() {
+		var link ProductSupplier
+		err := scanProductSupplier(rows, &link)
+
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+// GetProductSuppliers lista os fornecedores de um produto. O acesso ao
+// produto deve ser verificado pelo chamador.
+func GetProductSuppliers(productId int64) ([]ProductSupplier, error) {
+	return getProductSuppliers("ps.product_id", productId)
+}
+
+// GetSupplierProducts lista os produtos vinculados a um fornecedor. O acesso
+// ao fornecedor deve ser verificado pelo chamador.
+func GetSupplierProducts(supplierId int64) ([]ProductSupplier, error) {
+	return getProductSuppliers("ps.supplier_id", supplierId)
+}
+
+// Save cria ou atualiza o vínculo entre produto e fornecedor, que precisam
+// pertencer ao mesmo estabelecimento.
+func (ps *ProductSupplier) Save(tx *sql.Tx) error {
+	var productEstabId, supplierEstabId int64
+
+	err := tx.QueryRow("SELECT estabelecimento_id, nome FROM products WHERE id = $1 AND deleted_at IS NULL", ps.ProductID).Scan(&productEstabId, &ps.ProductNome)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow("SELECT estabelecimento_id, razao_social FROM suppliers WHERE id = $1", ps.SupplierID).Scan(&supplierEstabId, &ps.SupplierNome)
+	if err != nil {
+		return err
+	}
+
+	if productEstabId != supplierEstabId {
+		return ErrSupplierEstablishmentMismatch
+	}
+
+	ps.EstabelecimentoID = productEstabId
+
+	query := `INSERT INTO product_suppliers(product_id, supplier_id, codigo_fornecedor, ultimo_custo)
+	VALUES($1, $2, $3, $4)
+	ON CONFLICT (product_id, supplier_id)
+	DO UPDATE SET codigo_fornecedor = EXCLUDED.codigo_fornecedor, ultimo_custo = EXCLUDED.ultimo_custo, updated_at = NOW()
+	RETURNING created_at, updated_at`
+
+	return tx.QueryRow(query, ps.ProductID, ps.SupplierID, ps.CodigoFornecedor, ps.UltimoCusto).Scan(&ps.CreatedAt, &ps.UpdatedAt)
+}
+
+func (ps *ProductSupplier) Delete(tx *sql.Tx) error {
+	result, err := tx.Exec("DELETE FROM product_suppliers WHERE product_id = $1 AND supplier_id = $2", ps.ProductID, ps.SupplierID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
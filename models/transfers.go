@@ -0,0 +1,360 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	TransferSolicitada = "SOLICITADA"
+	TransferEmTransito = "EM_TRANSITO"
+	TransferRecebida   = "RECEBIDA"
+	TransferCancelada  = "CANCELADA"
+)
+
+var (
+	ErrInvalidTransferStatus = errors.New("a transferência não está em um status que permita essa operação")
+	ErrSameEstablishment     = errors.New("origem e destino da transferência devem ser estabelecimentos diferentes")
+	ErrTransferDestination   = errors.New("estabelecimento de destino não encontrado")
+)
+
+type Transfer struct {
+	ID                       int64      `json:"id"`
+	SKU                      string     `json:"sku" binding:"required"`
+	Quantidade               float64    `json:"quantidade" binding:"required,gt=0"`
+	Status                   string     `json:"status"`
+	Observacao               string     `json:"observacao"`
+	OrigemEstabelecimentoID  int64      `json:"origem_estabelecimento_id" binding:"required"`
+	DestinoEstabelecimentoID int64      `json:"destino_estabelecimento_id" binding:"required"`
+	OrigemProductID          int64      `json:"origem_product_id"`
+	DestinoProductID         *int64     `json:"destino_product_id"`
+	SolicitadoPor            *int64     `json:"solicitado_por"`
+	EnviadoPor               *int64     `json:"enviado_por"`
+	RecebidoPor              *int64     `json:"recebido_por"`
+	EnviadoEm                *time.Time `json:"enviado_em"`
+	RecebidoEm               *time.Time `json:"recebido_em"`
+	CreatedAt                time.Time  `json:"created_at"`
+	UpdatedAt                time.Time  `json:"updated_at"`
+}
+
+const transferColumns = `id, sku, quantidade, status, observacao, origem_estabelecimento_id, destino_estabelecimento_id,
+	origem_product_id, destino_product_id, solicitado_por, enviado_por, recebido_por, enviado_em, recebido_em, created_at, updated_at`
+
+func scanTransfer(row rowScanner, t *Transfer) error {
+	return row.Scan(
+		&t.ID, &t.SKU, &t.Quantidade, &t.Status, &t.Observacao, &t.OrigemEstabelecimentoID, &t.DestinoEstabelecimentoID,
+		&t.OrigemProductID, &t.DestinoProductID, &t.SolicitadoPor, &t.EnviadoPor, &t.RecebidoPor, &t.EnviadoEm, &t.RecebidoEm, &t.CreatedAt, &t.UpdatedAt,
+	)
+}
+
+func (t *Transfer) Save(tx *sql.Tx, role string, userId int64) error {
+	if t.OrigemEstabelecimentoID == t.DestinoEstabelecimentoID {
+		return ErrSameEstablishment
+	}
+
+	err := CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), t.OrigemEstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	var destinoExiste bool
+	err = tx.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM estabelecimentos WHERE id = $1 AND deleted_at IS NULL)",
+		t.DestinoEstabelecimentoID,
+	).Scan(&destinoExiste)
+	if err != nil {
+		return err
+	}
+
+	if !destinoExiste {
+		return ErrTransferDestination
+	}
+
+	var estoque float64
+	var unidade string
+	err = tx.QueryRow(
+		"SELECT id, estoque, unidade FROM products WHERE sku = $1 AND estabelecimento_id = $2 AND deleted_at IS NULL",
+		t.SKU, t.OrigemEstabelecimentoID,
+	).Scan(&t.OrigemProductID, &estoque, &unidade)
+	if err != nil {
+		return err
+	}
+
+	err = checkUnitQuantity(unidade, t.Quantidade)
+	if err != nil {
+		return err
+	}
+
+	if estoque < t.Quantidade {
+		return ErrInsufficientStock
+	}
+
+	t.Status = TransferSolicitada
+	t.SolicitadoPor = &userId
+
+	query := `INSERT INTO stock_transfers(sku, quantidade, status, observacao, origem_estabelecimento_id, destino_estabelecimento_id, origem_product_id, solicitado_por)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, updated_at`
+
+	return tx.QueryRow(
+		query,
+		t.SKU, t.Quantidade, t.Status, t.Observacao, t.OrigemEstabelecimentoID, t.DestinoEstabelecimentoID, t.OrigemProductID, userId,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func GetAllTransfers(role, userId string) ([]Transfer, error) {
+	query := "SELECT " + transferColumns + " FROM stock_transfers"
+	args := []interface{}{}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, err
+		}
+
+		query += " WHERE origem_estabelecimento_id = $1 OR destino_estabelecimento_id = $1"
+		args = append(args, estabelecimentoId)
+	}
+
+	query += " ORDER BY created_at DESC, id DESC"
+
+	rows, err := db.DB.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var transfers []Transfer
+
+	for rows.Next() {
+		var transfer Transfer
+		err := scanTransfer(rows, &transfer)
+
+		if err != nil {
+			return nil, err
+		}
+
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, nil
+}
+
+func GetTransfer(id int64, role, userId string) (*Transfer, error) {
+	row := db.DB.QueryRow("SELECT "+transferColumns+" FROM stock_transfers WHERE id = $1", id)
+
+	var transfer Transfer
+
+	err := scanTransfer(row, &transfer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, fmt.Errorf("não foi possível obter estabelecimento do usuário: %w", err)
+		}
+
+		if estabelecimentoId != transfer.OrigemEstabelecimentoID && estabelecimentoId != transfer.DestinoEstabelecimentoID {
+			return nil, ErrAccessDenied
+		}
+	}
+
+	return &transfer, nil
+}
+
+func (t *Transfer) lock(tx *sql.Tx) error {
+	row := tx.QueryRow("SELECT "+transferColumns+" FROM stock_transfers WHERE id = $1 FOR UPDATE", t.ID)
+	return scanTransfer(row, t)
+}
+
+// Dispatch retira o estoque da origem e coloca a transferência em trânsito.
+func (t *Transfer) Dispatch(tx *sql.Tx, role string, userId int64) error {
+	err := t.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if t.Status != TransferSolicitada {
+		return ErrInvalidTransferStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), t.OrigemEstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	movement := StockMovement{
+		ProductID:  t.OrigemProductID,
+		Tipo:       MovementSaida,
+		Quantidade: t.Quantidade,
+		Motivo:     fmt.Sprintf("Transferência #%d enviada ao estabelecimento %d", t.ID, t.DestinoEstabelecimentoID),
+		UserID:     userId,
+	}
+
+	err = movement.Save(tx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	t.Status = TransferEmTransito
+	t.EnviadoPor = &userId
+	t.EnviadoEm = &now
+	t.UpdatedAt = now
+
+	_, err = tx.Exec(
+		"UPDATE stock_transfers SET status = $1, enviado_por = $2, enviado_em = $3, updated_at = $3 WHERE id = $4",
+		t.Status, userId, now, t.ID,
+	)
+
+	return err
+}
+
+// Receive dá entrada no destino, criando o produto com o mesmo SKU caso o
+// estabelecimento de destino ainda não o possua.
+func (t *Transfer) Receive(tx *sql.Tx, role string, userId int64) error {
+	err := t.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if t.Status != TransferEmTransito {
+		return ErrInvalidTransferStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), t.DestinoEstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	// Um produto excluído no destino com o mesmo SKU é restaurado, já que o
+	// índice único por estabelecimento impede criar outro.
+	var destinoProductId int64
+	var destinoDeletedAt *time.Time
+	err = tx.QueryRow(
+		"SELECT id, deleted_at FROM products WHERE sku = $1 AND estabelecimento_id = $2",
+		t.SKU, t.DestinoEstabelecimentoID,
+	).Scan(&destinoProductId, &destinoDeletedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		var origem Product
+		err = tx.QueryRow(
+			"SELECT nome, descricao, valor, unidade, unidade_compra, fator_compra, unidade_venda, fator_venda FROM products WHERE id = $1",
+			t.OrigemProductID,
+		).Scan(&origem.Nome, &origem.Descricao, &origem.Valor, &origem.Unidade, &origem.UnidadeCompra, &origem.FatorCompra, &origem.UnidadeVenda, &origem.FatorVenda)
+		if err != nil {
+			return err
+		}
+
+		destino := Product{
+			Nome:              origem.Nome,
+			Descricao:         origem.Descricao,
+			Valor:             origem.Valor,
+			Unidade:           origem.Unidade,
+			UnidadeCompra:     origem.UnidadeCompra,
+			FatorCompra:       origem.FatorCompra,
+			UnidadeVenda:      origem.UnidadeVenda,
+			FatorVenda:        origem.FatorVenda,
+			SKU:               t.SKU,
+			EstabelecimentoID: t.DestinoEstabelecimentoID,
+		}
+
+		err = destino.Save(tx, userId)
+		if err != nil {
+			return err
+		}
+
+		destinoProductId = destino.ID
+	} else if err != nil {
+		return err
+	} else if destinoDeletedAt != nil {
+		destino := Product{ID: destinoProductId}
+		err = destino.Restore(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// A entrada no destino leva o custo vigente do produto na origem.
+	custo, err := productUnitCost(tx, t.OrigemProductID)
+	if err != nil {
+		return err
+	}
+
+	movement := StockMovement{
+		ProductID:     destinoProductId,
+		Tipo:          MovementEntrada,
+		Quantidade:    t.Quantidade,
+		Motivo:        fmt.Sprintf("Transferência #%d recebida do estabelecimento %d", t.ID, t.OrigemEstabelecimentoID),
+		UserID:        userId,
+		CustoUnitario: &custo,
+	}
+
+	err = movement.Save(tx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	t.Status = TransferRecebida
+	t.DestinoProductID = &destinoProductId
+	t.RecebidoPor = &userId
+	t.RecebidoEm = &now
+	t.UpdatedAt = now
+
+	_, err = tx.Exec(
+		"UPDATE stock_transfers SET status = $1, destino_product_id = $2, recebido_por = $3, recebido_em = $4, updated_at = $4 WHERE id = $5",
+		t.Status, destinoProductId, userId, now, t.ID,
+	)
+
+	return err
+}
+
+// Cancel cancela a transferência. Se ela já estava em trânsito, o estoque
+// retorna para a origem.
+func (t *Transfer) Cancel(tx *sql.Tx, role string, userId int64) error {
+	err := t.lock(tx)
+	if err != nil {
+		return err
+	}
+
+	if t.Status != TransferSolicitada && t.Status != TransferEmTransito {
+		return ErrInvalidTransferStatus
+	}
+
+	err = CheckEstablishmentAccess(role, fmt.Sprintf("%d", userId), t.OrigemEstabelecimentoID)
+	if err != nil {
+		return err
+	}
+
+	if t.Status == TransferEmTransito {
+		movement := StockMovement{
+			ProductID:  t.OrigemProductID,
+			Tipo:       MovementEntrada,
+			Quantidade: t.Quantidade,
+			Motivo:     fmt.Sprintf("Estorno da transferência #%d cancelada", t.ID),
+			UserID:     userId,
+		}
+
+		err = movement.Save(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	t.Status = TransferCancelada
+	t.UpdatedAt = time.Now()
+
+	_, err = tx.Exec("UPDATE stock_transfers SET status = $1, updated_at = $2 WHERE id = $3", t.Status, t.UpdatedAt, t.ID)
+
+	return err
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+const (
+	UnitUnidade    = "UN"
+	UnitQuilograma = "KG"
+	UnitLitro      = "L"
+	UnitCaixa      = "CX"
+)
+
+var (
+	ErrInvalidUnit        = errors.New("unidade de medida ou fator de conversão inválido")
+	ErrFractionalQuantity = errors.New("a unidade de medida não aceita quantidades fracionadas")
+)
+
+// UnitOfMeasure descreve uma unidade aceita pelos produtos. Unidades não
+// fracionáveis só aceitam quantidades inteiras.
+type UnitOfMeasure struct {
+	Sigla       string `json:"sigla"`
+	Nome        string `json:"nome"`
+	Fracionavel bool   `json:"fracionavel"`
+}
+
+var UnitsOfMeasure = []UnitOfMeasure{
+	{Sigla: UnitUnidade, Nome: "Unidade", Fracionavel: false},
+	{Sigla: UnitQuilograma, Nome: "Quilograma", Fracionavel: true},
+	{Sigla: UnitLitro, Nome: "Litro", Fracionavel: true},
+	{Sigla: UnitCaixa, Nome: "Caixa", Fracionavel: false},
+}
+
+func findUnit(sigla string) (UnitOfMeasure, bool) {
+	for _, unit := range UnitsOfMeasure {
+		if unit.Sigla == sigla {
+			return unit, true
+		}
+	}
+
+	return UnitOfMeasure{}, false
+}
+
+func isWholeQuantity(quantidade float64) bool {
+	quantidade = roundQuantity(quantidade)
+	return quantidade == math.Trunc(quantidade)
+}
+
+// checkUnitQuantity rejeita quantidades fracionadas em unidades inteiras.
+func checkUnitQuantity(unidade string, quantidade float64) error {
+	unit, ok := findUnit(unidade)
+	if ok && !unit.Fracionavel && !isWholeQuantity(quantidade) {
+		return ErrFractionalQuantity
+	}
+
+	return nil
+}
+
+// normalizeUnitFactor valida uma unidade de compra ou venda e o fator que a
+// converte para a unidade de estoque. Sem unidade, vale a de estoque.
+func normalizeUnitFactor(estoque UnitOfMeasure, unidade *string, fator *float64) error {
+	*unidade = strings.ToUpper(strings.TrimSpace(*unidade))
+	if *unidade == "" {
+		*unidade = estoque.Sigla
+	}
+
+	if _, ok := findUnit(*unidade); !ok {
+		return ErrInvalidUnit
+	}
+
+	*fator = roundQuantity(*fator)
+	if *fator == 0 && *unidade == estoque.Sigla {
+		*fator = 1
+	}
+
+	if *fator <= 0 || (*unidade == estoque.Sigla && *fator != 1) {
+		return ErrInvalidUnit
+	}
+
+	// Uma unidade convertida para estoque inteiro precisa render um número
+	// inteiro de unidades de estoque.
+	if !estoque.Fracionavel && !isWholeQuantity(*fator) {
+		return ErrInvalidUnit
+	}
+
+	return nil
+}
+
+// normalizeUnits valida as unidades do produto, preenchendo os padrões.
+func (p *Product) normalizeUnits() error {
+	p.Unidade = strings.ToUpper(strings.TrimSpace(p.Unidade))
+	if p.Unidade == "" {
+		p.Unidade = UnitUnidade
+	}
+
+	estoque, ok := findUnit(p.Unidade)
+	if !ok {
+		return ErrInvalidUnit
+	}
+
+	err := normalizeUnitFactor(estoque, &p.UnidadeCompra, &p.FatorCompra)
+	if err != nil {
+		return err
+	}
+
+	return normalizeUnitFactor(estoque, &p.UnidadeVenda, &p.FatorVenda)
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckUnitQuantity(t *testing.T) {
+	tests := []struct {
+		name       string
+		unidade    string
+		quantidade float64
+		wantErr    error
+	}{
+		{"inteira em unidade", UnitUnidade, 3, nil},
+		{"fracionada em unidade", UnitUnidade, 2.5, ErrFractionalQuantity},
+		{"fracionada em caixa", UnitCaixa, 1.5, ErrFractionalQuantity},
+		{"resíduo abaixo da precisão", UnitUnidade, 2.0004, nil},
+		{"arredonda para inteiro", UnitUnidade, 1.9996, nil},
+		{"fracionada em quilograma", UnitQuilograma, 0.735, nil},
+		{"fracionada em litro", UnitLitro, 0.001, nil},
+		{"unidade desconhecida não é verificada", "XX", 1.5, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkUnitQuantity(tt.unidade, tt.quantidade)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkUnitQuantity(%q, %v) error = %v, want %v", tt.unidade, tt.quantidade, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeUnitFactor(t *testing.T) {
+	unidade, _ := findUnit(UnitUnidade)
+	quilograma, _ := findUnit(UnitQuilograma)
+
+	tests := []struct {
+		name        string
+		estoque     UnitOfMeasure
+		unidade     string
+		fator       float64
+		wantUnidade string
+		wantFator   float64
+		wantErr     error
+	}{
+		{"sem unidade usa a de estoque", unidade, "", 0, UnitUnidade, 1, nil},
+		{"normaliza a sigla", unidade, " cx ", 12, UnitCaixa, 12, nil},
+		{"unidade de estoque com fator 1", unidade, "un", 0, UnitUnidade, 1, nil},
+		{"unidade de estoque com outro fator", unidade, UnitUnidade, 2, UnitUnidade, 2, ErrInvalidUnit},
+		{"sem fator", unidade, UnitCaixa, 0, UnitCaixa, 0, ErrInvalidUnit},
+		{"fator negativo", unidade, UnitCaixa, -1, UnitCaixa, -1, ErrInvalidUnit},
+		{"fator fracionado para estoque inteiro", unidade, UnitCaixa, 12.5, UnitCaixa, 12.5, ErrInvalidUnit},
+		{"fator arredondado para inteiro", unidade, UnitCaixa, 11.9996, UnitCaixa, 12, nil},
+		{"fator fracionado para estoque fracionável", quilograma, UnitCaixa, 2.5004, UnitCaixa, 2.5, nil},
+		{"fator que arredonda para zero", quilograma, UnitCaixa, 0.0004, UnitCaixa, 0, ErrInvalidUnit},
+		{"unidade desconhecida", unidade, "XX", 1, "XX", 1, ErrInvalidUnit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unidade, fator := tt.unidade, tt.fator
+
+			err := normalizeUnitFactor(tt.estoque, &unidade, &fator)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("normalizeUnitFactor() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if unidade != tt.wantUnidade {
+				t.Errorf("unidade = %q, want %q", unidade, tt.wantUnidade)
+			}
+
+			if fator != tt.wantFator {
+				t.Errorf("fator = %v, want %v", fator, tt.wantFator)
+			}
+		})
+	}
+}
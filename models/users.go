@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"time"
@@ -21,23 +22,36 @@ type User struct {
 	EstabelecimentoID int64     `json:"estabelecimento_id" binding:"required"`
 }
 
+var ErrAccessDenied = errors.New("acesso negado")
+
 type LoginInput struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
+// SignupInput é o cadastro público: cria um novo estabelecimento junto com o
+// seu primeiro OWNER.
+type SignupInput struct {
+	Nome            string        `json:"nome" binding:"required"`
+	Sobrenome       string        `json:"sobrenome" binding:"required"`
+	Email           string        `json:"email" binding:"required"`
+	Password        string        `json:"password" binding:"required"`
+	Estabelecimento Establishment `json:"estabelecimento" binding:"required"`
+}
+
 type PublicUser struct {
-	ID                int64     `json:"id"`
-	Nome              string    `json:"nome"`
-	Sobrenome         string    `json:"sobrenome"`
-	Email             string    `json:"email"`
-	Role              string    `json:"role"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
-	EstabelecimentoID int64     `json:"estabelecimento_id"`
+	ID                int64      `json:"id"`
+	Nome              string     `json:"nome"`
+	Sobrenome         string     `json:"sobrenome"`
+	Email             string     `json:"email"`
+	Role              string     `json:"role"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	EstabelecimentoID int64      `json:"estabelecimento_id"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
 }
 
-func (u *User) Save() error {
+func (u *User) Save(tx *sql.Tx) error {
 	query := `INSERT INTO users(nome, sobrenome, email, password, role, estabelecimento_id)
 	          VALUES ($1, $2, $3, $4, $5, $6)
 	          RETURNING id;`
@@ -47,7 +61,7 @@ func (u *User) Save() error {
 		return err
 	}
 
-	err = db.DB.QueryRow(query,
+	err = tx.QueryRow(query,
 		u.Nome,
 		u.Sobrenome,
 		u.Email,
@@ -63,8 +77,61 @@ func (u *User) Save() error {
 	return nil
 }
 
+// Public devolve a visão do usuário sem a senha.
+func (u *User) Public() PublicUser {
+	return PublicUser{
+		ID:                u.ID,
+		Nome:              u.Nome,
+		Sobrenome:         u.Sobrenome,
+		Email:             u.Email,
+		Role:              u.Role,
+		CreatedAt:         u.CreatedAt,
+		UpdatedAt:         u.UpdatedAt,
+		EstabelecimentoID: u.EstabelecimentoID,
+	}
+}
+
+func GetUserEstablishmentID(userId string) (int64, error) {
+	var estabelecimentoId int64
+	err := db.DB.QueryRow("SELECT estabelecimento_id FROM users WHERE id = $1", userId).Scan(&estabelecimentoId)
+	return estabelecimentoId, err
+}
+
+// IsGlobalOwner informa se o usuário é um OWNER com acesso a todos os
+// estabelecimentos. O OWNER criado pelo cadastro público ou por convite fica
+// restrito ao próprio estabelecimento, como os demais papéis.
+func IsGlobalOwner(role, userId string) bool {
+	if role != "OWNER" {
+		return false
+	}
+
+	var acessoGlobal bool
+	err := db.DB.QueryRow("SELECT acesso_global FROM users WHERE id = $1", userId).Scan(&acessoGlobal)
+
+	return err == nil && acessoGlobal
+}
+
+// CheckEstablishmentAccess libera OWNERs globais para qualquer estabelecimento
+// e os demais usuários apenas para o estabelecimento ao qual pertencem.
+func CheckEstablishmentAccess(role, userId string, estabelecimentoId int64) error {
+	if IsGlobalOwner(role, userId) {
+		return nil
+	}
+
+	userEstabelecimentoId, err := GetUserEstablishmentID(userId)
+	if err != nil {
+		return err
+	}
+
+	if userEstabelecimentoId != estabelecimentoId {
+		return ErrAccessDenied
+	}
+
+	return nil
+}
+
 func (u *User) ValidateCredentials() error {
-	query := "SELECT id, nome, sobrenome, password, created_at, updated_at, role, estabelecimento_id FROM users WHERE email = $1"
+	query := "SELECT id, nome, sobrenome, password, created_at, updated_at, role, estabelecimento_id FROM users WHERE email = $1 AND deleted_at IS NULL"
 	row := db.DB.QueryRow(query, u.Email)
 
 	var retrievedPassword string
@@ -95,13 +162,47 @@ func (u *User) ValidateCredentials() error {
 	return nil
 }
 
-func GetAllUsers() ([]PublicUser, error) {
-	query := "SELECT id, nome, sobrenome, email, created_at, updated_at, role, estabelecimento_id FROM users"
+var UserSortColumns = map[string]string{
+	"id":                 "id",
+	"nome":               "nome",
+	"sobrenome":          "sobrenome",
+	"email":              "email",
+	"role":               "role",
+	"estabelecimento_id": "estabelecimento_id",
+	"created_at":         "created_at",
+}
+
+func GetAllUsers(role, userId string, params ListParams, includeDeleted bool) ([]PublicUser, ListResult, error) {
+	baseQuery := "SELECT id, nome, sobrenome, email, created_at, updated_at, role, estabelecimento_id, deleted_at FROM users WHERE 1=1"
+	args := []interface{}{}
 
-	rows, err := db.DB.Query(query)
+	if !includeDeleted {
+		baseQuery += " AND deleted_at IS NULL"
+	}
+
+	if !IsGlobalOwner(role, userId) {
+		estabelecimentoId, err := GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, ListResult{}, err
+		}
+
+		args = append(args, estabelecimentoId)
+		baseQuery += fmt.Sprintf(" AND estabelecimento_id = $%d", len(args))
+	}
+
+	countQuery, pageQuery, pageArgs := buildListQueries(baseQuery, args, params, "id")
+
+	var total int64
+	err := db.DB.QueryRow(countQuery, args...).Scan(&total)
 
 	if err != nil {
-		return nil, err
+		return nil, ListResult{}, err
+	}
+
+	rows, err := db.DB.Query(pageQuery, pageArgs...)
+
+	if err != nil {
+		return nil, ListResult{}, err
 	}
 
 	defer rows.Close()
@@ -110,33 +211,56 @@ func GetAllUsers() ([]PublicUser, error) {
 
 	for rows.Next() {
 		var user PublicUser
-		err := rows.Scan(&user.ID, &user.Nome, &user.Sobrenome, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.EstabelecimentoID)
+		err := rows.Scan(&user.ID, &user.Nome, &user.Sobrenome, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.EstabelecimentoID, &user.DeletedAt)
 
 		if err != nil {
-			return nil, err
+			return nil, ListResult{}, err
 		}
 
 		users = append(users, user)
 	}
 
-	return users, nil
+	fetched := len(users)
+	if fetched > params.PerPage {
+		users = users[:params.PerPage]
+	}
+
+	var lastID int64
+	if len(users) > 0 {
+		lastID = users[len(users)-1].ID
+	}
+
+	return users, params.result(total, fetched, lastID), nil
 
 }
 
 func GetUserById(id int64, role, userId string) (*PublicUser, error) {
-	query := "SELECT id, nome, sobrenome, email, created_at, updated_at, role, estabelecimento_id FROM users WHERE id = $1"
+	return findUser(id, role, userId, false)
+}
+
+// GetUserByIdWithDeleted também encontra usuários excluídos, para restauração
+// e expurgo.
+func GetUserByIdWithDeleted(id int64, role, userId string) (*PublicUser, error) {
+	return findUser(id, role, userId, true)
+}
+
+func findUser(id int64, role, userId string, includeDeleted bool) (*PublicUser, error) {
+	query := "SELECT id, nome, sobrenome, email, created_at, updated_at, role, estabelecimento_id, deleted_at FROM users WHERE id = $1"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 
 	row := db.DB.QueryRow(query, id)
 
 	var user PublicUser
 
-	err := row.Scan(&user.ID, &user.Nome, &user.Sobrenome, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.EstabelecimentoID)
+	err := row.Scan(&user.ID, &user.Nome, &user.Sobrenome, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.Role, &user.EstabelecimentoID, &user.DeletedAt)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if role != "OWNER" {
+	if !IsGlobalOwner(role, userId) {
 		var userEstabelecimentoId int64
 		err := db.DB.QueryRow("SELECT estabelecimento_id FROM users WHERE id = $1", userId).Scan(&userEstabelecimentoId)
 		if err != nil {
@@ -151,10 +275,10 @@ func GetUserById(id int64, role, userId string) (*PublicUser, error) {
 	return &user, nil
 }
 
-func (u *PublicUser) Update(role string) error {
+func (u *PublicUser) Update(tx *sql.Tx, role string) error {
 	if role != "OWNER" {
 		var currentEstabID int64
-		err := db.DB.QueryRow("SELECT estabelecimento_id FROM users WHERE id = $1", u.ID).Scan(&currentEstabID)
+		err := tx.QueryRow("SELECT estabelecimento_id FROM users WHERE id = $1", u.ID).Scan(&currentEstabID)
 		if err != nil {
 			return err
 		}
@@ -165,7 +289,7 @@ func (u *PublicUser) Update(role string) error {
 	SET nome = $1, sobrenome = $2, email = $3, updated_at = $4, role = $5
 	WHERE id = $6`
 
-	stmt, err := db.DB.Prepare(query)
+	stmt, err := tx.Prepare(query)
 
 	if err != nil {
 		return err
@@ -182,10 +306,27 @@ func (u *PublicUser) Update(role string) error {
 	return nil
 }
 
-func (u *User) Delete() error {
+// Delete faz a exclusão lógica do usuário e revoga suas sessões.
+func (u *User) Delete(tx *sql.Tx) error {
+	_, err := tx.Exec("UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", u.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", u.ID)
+
+	return err
+}
+
+func (u *User) Restore(tx *sql.Tx) error {
+	_, err := tx.Exec("UPDATE users SET deleted_at = NULL, updated_at = NOW() WHERE id = $1", u.ID)
+	return err
+}
+
+func (u *User) Purge(tx *sql.Tx) error {
 	query := "DELETE FROM users WHERE id = $1"
 
-	stmt, err := db.DB.Prepare(query)
+	stmt, err := tx.Prepare(query)
 
 	if err != nil {
 		return err
@@ -0,0 +1,299 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+)
+
+const (
+	CostingMedio = "MEDIO"
+	CostingPEPS  = "PEPS"
+)
+
+func roundCost(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
+
+type costLayer struct {
+	quantidade float64
+	custo      float64
+}
+
+// costLedger reconstrói o custo de um produto aplicando suas movimentações em
+// ordem. No custo médio, cada entrada recalcula a média ponderada; no PEPS,
+// cada entrada vira uma camada e as saídas consomem as camadas mais antigas.
+// Entradas sem custo entram pelo custo vigente. Saldo negativo não tem valor:
+// a próxima entrada cobre primeiro o que faltava.
+//
+// O método segue o gravado em cada movimentação. Na troca, o saldo passa ao
+// novo método pelo custo vigente: vira uma única camada no PEPS ou a média no
+// custo médio.
+type costLedger struct {
+	metodo      string
+	quantidade  float64
+	custoMedio  float64
+	camadas     []costLayer
+	ultimoCusto float64
+}
+
+func (l *costLedger) switchMethod(metodo string) {
+	if metodo == l.metodo {
+		return
+	}
+
+	custo := l.unitCost()
+	l.metodo = metodo
+
+	if metodo == CostingPEPS {
+		l.camadas = nil
+		if l.quantidade > 0 {
+			l.camadas = []costLayer{{quantidade: l.quantidade, custo: custo}}
+		}
+		l.ultimoCusto = custo
+		return
+	}
+
+	l.camadas = nil
+	l.custoMedio = custo
+}
+
+func (l *costLedger) apply(delta float64, custoUnitario *float64) {
+	if delta > 0 {
+		l.entry(delta, custoUnitario)
+		return
+	}
+
+	l.exit(-delta)
+}
+
+func (l *costLedger) entry(quantidade float64, custoUnitario *float64) {
+	custo := l.unitCost()
+	if custoUnitario != nil {
+		custo = *custoUnitario
+	}
+	l.ultimoCusto = custo
+
+	if l.metodo == CostingPEPS {
+		faltante := math.Max(-l.quantidade, 0)
+		l.quantidade = roundQuantity(l.quantidade + quantidade)
+		if restante := roundQuantity(quantidade - faltante); restante > 0 {
+			l.camadas = append(l.camadas, costLayer{quantidade: restante, custo: custo})
+		}
+		return
+	}
+
+	if l.quantidade <= 0 {
+		l.custoMedio = custo
+	} else {
+		l.custoMedio = (l.quantidade*l.custoMedio + quantidade*custo) / (l.quantidade + quantidade)
+	}
+	l.quantidade = roundQuantity(l.quantidade + quantidade)
+}
+
+func (l *costLedger) exit(quantidade float64) {
+	l.quantidade = roundQuantity(l.quantidade - quantidade)
+
+	if l.metodo != CostingPEPS {
+		return
+	}
+
+	for quantidade > 0 && len(l.camadas) > 0 {
+		camada := &l.camadas[0]
+		if camada.quantidade > quantidade {
+			camada.quantidade = roundQuantity(camada.quantidade - quantidade)
+			return
+		}
+
+		quantidade = roundQuantity(quantidade - camada.quantidade)
+		l.camadas = l.camadas[1:]
+	}
+}
+
+func (l *costLedger) value() float64 {
+	if l.quantidade <= 0 {
+		return 0
+	}
+
+	if l.metodo != CostingPEPS {
+		return l.quantidade * l.custoMedio
+	}
+
+	var valor float64
+	for _, camada := range l.camadas {
+		valor += camada.quantidade * camada.custo
+	}
+
+	return valor
+}
+
+// unitCost é o custo unitário vigente: o valor do saldo dividido pela
+// quantidade ou, sem saldo, o último custo conhecido.
+func (l *costLedger) unitCost() float64 {
+	if l.metodo != CostingPEPS {
+		return l.custoMedio
+	}
+
+	if l.quantidade > 0 && len(l.camadas) > 0 {
+		return l.value() / l.quantidade
+	}
+
+	return l.ultimoCusto
+}
+
+// productUnitCost calcula o custo unitário vigente do produto pelo método do
+// seu estabelecimento, como custo de entrada do mesmo item em outro
+// estabelecimento.
+func productUnitCost(tx *sql.Tx, productId int64) (float64, error) {
+	var metodo string
+	err := tx.QueryRow(
+		"SELECT e.metodo_custeio FROM products p JOIN estabelecimentos e ON e.id = p.estabelecimento_id WHERE p.id = $1",
+		productId,
+	).Scan(&metodo)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(`SELECT metodo_custeio, estoque_posterior - estoque_anterior, custo_unitario
+	FROM stock_movements
+	WHERE product_id = $1
+	ORDER BY created_at, id`, productId)
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	ledger := costLedger{metodo: metodo}
+	for rows.Next() {
+		var metodoMovimentacao string
+		var delta float64
+		var custo *float64
+		err := rows.Scan(&metodoMovimentacao, &delta, &custo)
+		if err != nil {
+			return 0, err
+		}
+
+		ledger.switchMethod(metodoMovimentacao)
+		ledger.apply(delta, custo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	ledger.switchMethod(metodo)
+
+	return roundCost(ledger.unitCost()), nil
+}
+
+type InventoryValuationItem struct {
+	ProductID         int64   `json:"product_id"`
+	ProductNome       string  `json:"product_nome"`
+	SKU               string  `json:"sku"`
+	EstabelecimentoID int64   `json:"estabelecimento_id"`
+	MetodoCusteio     string  `json:"metodo_custeio"`
+	Quantidade        float64 `json:"quantidade"`
+	CustoUnitario     float64 `json:"custo_unitario"`
+	Valor             float64 `json:"valor"`
+}
+
+type InventoryValuation struct {
+	Data       string                   `json:"data"`
+	Itens      []InventoryValuationItem `json:"itens"`
+	ValorTotal float64                  `json:"valor_total"`
+}
+
+// GetInventoryValuation avalia o estoque ao fim do dia informado, refazendo
+// as movimentações de cada produto até lá pelo método de custeio gravado em
+// cada uma. O produto conta no estabelecimento da sua última movimentação até
+// a data, e os excluídos antes dela ficam de fora. Kits também ficam de fora,
+// pois seu estoque está nos componentes. OWNERs globais veem todos os
+// estabelecimentos, ou só o informado em estabelecimentoId; os demais papéis,
+// apenas o próprio.
+func GetInventoryValuation(role, userId string, estabelecimentoId int64, data time.Time) (*InventoryValuation, error) {
+	query := `SELECT p.id, p.nome, p.sku, m.estabelecimento_id, m.metodo_custeio, m.estoque_posterior - m.estoque_anterior, m.custo_unitario
+	FROM stock_movements m
+	JOIN products p ON p.id = m.product_id
+	WHERE p.tipo <> 'KIT' AND m.created_at < $1
+	AND (p.deleted_at IS NULL OR p.deleted_at >= $1)`
+	args := []interface{}{data.AddDate(0, 0, 1)}
+
+	if !IsGlobalOwner(role, userId) {
+		var err error
+		estabelecimentoId, err = GetUserEstablishmentID(userId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if estabelecimentoId != 0 {
+		args = append(args, estabelecimentoId)
+		query += fmt.Sprintf(" AND m.product_id IN (SELECT product_id FROM stock_movements WHERE estabelecimento_id = $%d AND created_at < $1)", len(args))
+	}
+
+	query += " ORDER BY p.id, m.created_at, m.id"
+
+	rows, err := db.DB.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	valuation := &InventoryValuation{Data: data.Format("02/01/2006"), Itens: []InventoryValuationItem{}}
+
+	var item *InventoryValuationItem
+	var ledger costLedger
+
+	flush := func() {
+		if item == nil || ledger.quantidade == 0 {
+			return
+		}
+
+		if estabelecimentoId != 0 && item.EstabelecimentoID != estabelecimentoId {
+			return
+		}
+
+		item.Quantidade = ledger.quantidade
+		item.Valor = roundMoney(ledger.value())
+		item.CustoUnitario = roundCost(ledger.unitCost())
+		valuation.Itens = append(valuation.Itens, *item)
+		valuation.ValorTotal = roundMoney(valuation.ValorTotal + item.Valor)
+	}
+
+	for rows.Next() {
+		var current InventoryValuationItem
+		var delta float64
+		var custo *float64
+
+		err := rows.Scan(&current.ProductID, &current.ProductNome, &current.SKU, &current.EstabelecimentoID, &current.MetodoCusteio, &delta, &custo)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if item == nil || item.ProductID != current.ProductID {
+			flush()
+			item = &current
+			ledger = costLedger{metodo: current.MetodoCusteio}
+		}
+
+		item.EstabelecimentoID = current.EstabelecimentoID
+		item.MetodoCusteio = current.MetodoCusteio
+		ledger.switchMethod(current.MetodoCusteio)
+		ledger.apply(delta, custo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	flush()
+
+	return valuation, nil
+}
@@ -0,0 +1,132 @@
+package models
+
+import "testing"
+
+func cost(value float64) *float64 {
+	return &value
+}
+
+func TestCostLedger(t *testing.T) {
+	type step struct {
+		delta  float64
+		custo  *float64
+		metodo string
+	}
+
+	tests := []struct {
+		name       string
+		metodo     string
+		steps      []step
+		quantidade float64
+		valor      float64
+		custo      float64
+		camadas    []costLayer
+	}{
+		{
+			name:       "PEPS consome as camadas mais antigas",
+			metodo:     CostingPEPS,
+			steps:      []step{{10, cost(2), ""}, {10, cost(4), ""}, {-15, nil, ""}},
+			quantidade: 5,
+			valor:      20,
+			custo:      4,
+			camadas:    []costLayer{{quantidade: 5, custo: 4}},
+		},
+		{
+			name:       "PEPS sai por duas camadas e volta de saldo negativo",
+			metodo:     CostingPEPS,
+			steps:      []step{{10, cost(2), ""}, {10, cost(4), ""}, {-15, nil, ""}, {-7, nil, ""}, {4, cost(5), ""}},
+			quantidade: 2,
+			valor:      10,
+			custo:      5,
+			camadas:    []costLayer{{quantidade: 2, custo: 5}},
+		},
+		{
+			name:       "PEPS com saldo negativo não tem valor",
+			metodo:     CostingPEPS,
+			steps:      []step{{3, cost(2), ""}, {-5, nil, ""}},
+			quantidade: -2,
+			valor:      0,
+			custo:      2,
+		},
+		{
+			name:       "PEPS entrada sem custo usa o custo vigente",
+			metodo:     CostingPEPS,
+			steps:      []step{{4, cost(3), ""}, {2, nil, ""}},
+			quantidade: 6,
+			valor:      18,
+			custo:      3,
+			camadas:    []costLayer{{quantidade: 4, custo: 3}, {quantidade: 2, custo: 3}},
+		},
+		{
+			name:       "médio pondera as entradas",
+			metodo:     CostingMedio,
+			steps:      []step{{10, cost(2), ""}, {10, cost(4), ""}, {-15, nil, ""}},
+			quantidade: 5,
+			valor:      15,
+			custo:      3,
+		},
+		{
+			name:       "médio recomeça após saldo negativo",
+			metodo:     CostingMedio,
+			steps:      []step{{10, cost(2), ""}, {-12, nil, ""}, {4, cost(5), ""}},
+			quantidade: 2,
+			valor:      10,
+			custo:      5,
+		},
+		{
+			name:       "troca de médio para PEPS vira uma camada pelo custo médio",
+			metodo:     CostingMedio,
+			steps:      []step{{10, cost(2), ""}, {10, cost(4), ""}, {5, cost(6), CostingPEPS}, {-12, nil, ""}},
+			quantidade: 13,
+			valor:      54,
+			custo:      4.1538,
+			camadas:    []costLayer{{quantidade: 8, custo: 3}, {quantidade: 5, custo: 6}},
+		},
+		{
+			name:       "troca de PEPS para médio parte do custo das camadas",
+			metodo:     CostingPEPS,
+			steps:      []step{{10, cost(2), ""}, {10, cost(4), ""}, {-5, nil, CostingMedio}, {5, cost(6), ""}},
+			quantidade: 20,
+			valor:      75,
+			custo:      3.75,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := costLedger{metodo: tt.metodo}
+			for _, s := range tt.steps {
+				if s.metodo != "" {
+					ledger.switchMethod(s.metodo)
+				}
+				ledger.apply(s.delta, s.custo)
+			}
+
+			if ledger.quantidade != tt.quantidade {
+				t.Errorf("quantidade = %v, want %v", ledger.quantidade, tt.quantidade)
+			}
+
+			if got := roundMoney(ledger.value()); got != tt.valor {
+				t.Errorf("value() = %v, want %v", got, tt.valor)
+			}
+
+			if got := roundCost(ledger.unitCost()); got != tt.custo {
+				t.Errorf("unitCost() = %v, want %v", got, tt.custo)
+			}
+
+			if ledger.metodo != CostingPEPS {
+				return
+			}
+
+			if len(ledger.camadas) != len(tt.camadas) {
+				t.Fatalf("camadas = %+v, want %+v", ledger.camadas, tt.camadas)
+			}
+
+			for i := range tt.camadas {
+				if ledger.camadas[i] != tt.camadas[i] {
+					t.Errorf("camadas[%d] = %+v, want %+v", i, ledger.camadas[i], tt.camadas[i])
+				}
+			}
+		})
+	}
+}
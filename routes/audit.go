@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func actorFromContext(ctx *gin.Context) models.Actor {
+	userIdRaw, _ := ctx.Get("userId")
+	return models.Actor{UserID: userIdRaw.(int64), Role: ctx.GetString("role")}
+}
+
+func getAuditLog(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.AuditFilter{
+		Entidade:   ctx.Query("entidade"),
+		EntidadeID: ctx.Query("entidade_id"),
+		UserID:     ctx.Query("user_id"),
+		StartDate:  ctx.Query("data_inicial"),
+		EndDate:    ctx.Query("data_final"),
+	}
+
+	params, err := parseListParams(ctx, models.AuditSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	entries, result, err := models.GetAuditLog(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar o log de auditoria."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, entries, params, result))
+}
@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type refreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+func refreshToken(ctx *gin.Context) {
+	var input refreshTokenInput
+
+	err := ctx.ShouldBindJSON(&input)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O refresh_token é obrigatório"})
+		return
+	}
+
+	grant, err := models.RotateRefreshToken(input.RefreshToken)
+	if err != nil {
+		if errors.Is(err, models.ErrRefreshTokenReuse) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"message": "Refresh token reutilizado. Por segurança, a sessão foi encerrada."})
+			return
+		}
+
+		if errors.Is(err, models.ErrInvalidRefreshToken) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"message": "Refresh token inválido ou expirado"})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao renovar o token"})
+		return
+	}
+
+	token, err := utils.GenerateToken(grant.Email, grant.Role, grant.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao gerar token"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"token":         "Bearer " + token,
+		"refresh_token": grant.RefreshToken,
+	})
+}
+
+func logout(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	jti := ctx.GetString("jti")
+	expiresAt := ctx.GetTime("tokenExpiresAt")
+
+	var input refreshTokenInput
+
+	if ctx.ShouldBindJSON(&input) == nil {
+		err := models.RevokeRefreshToken(input.RefreshToken, userIdRaw.(int64))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao encerrar a sessão"})
+			return
+		}
+	}
+
+	err := models.RevokeAccessToken(jti, expiresAt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao encerrar a sessão"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logout realizado com sucesso"})
+}
@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+// barcodeErrorResponse responde aos erros de códigos de barras e de balança.
+// Retorna false quando o erro é de outro tipo.
+func barcodeErrorResponse(ctx *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, models.ErrInvalidBarcode):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Código de barras inválido. Informe um GTIN-8, GTIN-12, GTIN-13 ou GTIN-14 com dígito verificador correto."})
+	case errors.Is(err, models.ErrDuplicateBarcode):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Código de barras ou de balança já cadastrado em outro produto do estabelecimento."})
+	case errors.Is(err, models.ErrInvalidScaleCode):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Código de balança inválido. Informe 5 dígitos e o modo_balanca (PESO ou PRECO), com unidade de venda fracionável; PESO exige venda em KG."})
+	default:
+		return false
+	}
+
+	return true
+}
+
+func getProductBarcodes(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	barcodes, err := models.GetProductBarcodes(product.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os códigos de barras do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, barcodes)
+}
+
+func addProductBarcode(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	var barcode models.ProductBarcode
+
+	err = ctx.ShouldBindJSON(&barcode)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe o codigo."})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	barcode.ProductID = product.ID
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o código de barras. Falha interna."})
+		return
+	}
+
+	err = barcode.Save(tx)
+
+	if err != nil {
+		tx.Rollback()
+		if barcodeErrorResponse(ctx, err) {
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar o código de barras."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o código de barras. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, barcode)
+}
+
+func deleteProductBarcode(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao remover o código de barras. Falha interna."})
+		return
+	}
+
+	err = models.DeleteProductBarcode(tx, product.ID, ctx.Param("code"))
+
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Código de barras não encontrado no produto."})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível remover o código de barras."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao remover o código de barras. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Código de barras removido com sucesso."})
+}
+
+// getProductByBarcode atende a leitura do leitor de código de barras no caixa,
+// incluindo etiquetas de balança com peso ou preço.
+func getProductByBarcode(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	var estabelecimentoId int64
+	if raw := ctx.Query("estabelecimento_id"); raw != "" {
+		var err error
+		estabelecimentoId, err = strconv.ParseInt(raw, 10, 64)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o estabelecimento_id"})
+			return
+		}
+	}
+
+	lookup, err := models.FindProductByBarcode(ctx.Param("code"), estabelecimentoId, role, userIdStr)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrAccessDenied):
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem acesso a este estabelecimento."})
+		case errors.Is(err, models.ErrInvalidScaleCode):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "O produto da etiqueta de balança não tem preço para calcular a quantidade."})
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Nenhum produto encontrado com esse código de barras."})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível consultar o código de barras."})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lookup)
+}
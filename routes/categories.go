@@ -0,0 +1,240 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func categoryErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para alterar categorias deste estabelecimento."})
+	case errors.Is(err, models.ErrInvalidParentCategory):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Categoria pai inválida. Ela deve existir no mesmo estabelecimento e não pode ser uma subcategoria desta."})
+	case errors.Is(err, models.ErrCategoryHasChildren):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "A categoria possui subcategorias. Remova ou mova as subcategorias antes de deletá-la."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Categoria não encontrada."})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar a categoria."})
+	}
+}
+
+func createCategory(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	var category models.Category
+
+	err := ctx.ShouldBindJSON(&category)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe nome e estabelecimento_id."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar a categoria. Falha interna."})
+		return
+	}
+
+	err = category.Save(tx, role, userIdStr)
+
+	if err != nil {
+		tx.Rollback()
+		categoryErrorResponse(ctx, err)
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditCategory, category.ID, category.EstabelecimentoID, models.AuditCreate, nil, category)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar a categoria. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar a categoria. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, category)
+}
+
+func getCategories(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.CategoryFilter{
+		ParentID: ctx.Query("parent_id"),
+		Nome:     ctx.Query("nome"),
+	}
+
+	params, err := parseListParams(ctx, models.CategorySortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	categories, result, err := models.GetAllCategories(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as categorias."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, categories, params, result))
+}
+
+func getCategory(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	categoryId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	category, err := models.GetCategory(categoryId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Categoria não encontrada."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+}
+
+func updateCategory(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	categoryId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	category, err := models.GetCategory(categoryId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Categoria não encontrada."})
+		return
+	}
+
+	var updatedCategory models.Category
+
+	err = ctx.ShouldBindJSON(&updatedCategory)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Erro na requisição. Verifique os parametros obrigatórios e tente novamente."})
+		return
+	}
+
+	updatedCategory.ID = category.ID
+	updatedCategory.EstabelecimentoID = category.EstabelecimentoID
+	updatedCategory.CreatedAt = category.CreatedAt
+	updatedCategory.UpdatedAt = time.Now()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a categoria. Falha interna."})
+		return
+	}
+
+	err = updatedCategory.Update(tx)
+
+	if err != nil {
+		tx.Rollback()
+		categoryErrorResponse(ctx, err)
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditCategory, updatedCategory.ID, updatedCategory.EstabelecimentoID, models.AuditUpdate, category, updatedCategory)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a categoria. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a categoria. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedCategory)
+}
+
+func deleteCategory(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	categoryId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	category, err := models.GetCategory(categoryId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Categoria não encontrada."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar a categoria. Falha interna."})
+		return
+	}
+
+	err = category.Delete(tx)
+
+	if err != nil {
+		tx.Rollback()
+		categoryErrorResponse(ctx, err)
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditCategory, category.ID, category.EstabelecimentoID, models.AuditDelete, category, nil)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar a categoria. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar a categoria. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Categoria deletada com sucesso."})
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,7 +12,37 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// globalOwnerOnly restringe a operação aos OWNERs com acesso a todos os
+// estabelecimentos. O OWNER criado pelo cadastro público só administra o
+// próprio estabelecimento.
+func globalOwnerOnly(ctx *gin.Context) bool {
+	userIdRaw, _ := ctx.Get("userId")
+
+	if !models.IsGlobalOwner(ctx.GetString("role"), fmt.Sprintf("%d", userIdRaw.(int64))) {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para gerenciar outros estabelecimentos."})
+		return false
+	}
+
+	return true
+}
+
+func establishmentAccess(ctx *gin.Context, establishmentId int64) bool {
+	userIdRaw, _ := ctx.Get("userId")
+
+	err := models.CheckEstablishmentAccess(ctx.GetString("role"), fmt.Sprintf("%d", userIdRaw.(int64)), establishmentId)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem acesso a este estabelecimento."})
+		return false
+	}
+
+	return true
+}
+
 func createEstablishment(ctx *gin.Context) {
+	if !globalOwnerOnly(ctx) {
+		return
+	}
+
 	var establishment models.Establishment
 
 	err := ctx.ShouldBindJSON(&establishment)
@@ -65,6 +96,14 @@ func createEstablishment(ctx *gin.Context) {
 		return
 	}
 
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditEstablishment, establishment.ID, establishment.ID, models.AuditCreate, nil, establishment)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao criar o estabelecimento. Falha ao registrar a auditoria."})
+		return
+	}
+
 	err = tx.Commit()
 
 	if err != nil {
@@ -76,14 +115,30 @@ func createEstablishment(ctx *gin.Context) {
 }
 
 func getEstablishments(ctx *gin.Context) {
-	establishment, err := models.GetAllEstablishments()
+	if !globalOwnerOnly(ctx) {
+		return
+	}
+
+	params, err := parseListParams(ctx, models.EstablishmentSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
+
+	establishments, result, err := models.GetAllEstablishments(params, includeDeleted)
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possivel listar os estabelecimentos."})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, establishment)
+	ctx.JSON(http.StatusOK, listResponse(ctx, establishments, params, result))
 }
 
 func getEstablishment(ctx *gin.Context) {
@@ -94,7 +149,21 @@ func getEstablishment(ctx *gin.Context) {
 		return
 	}
 
-	establishment, err := models.GetEstablishmentByID(establishmentId)
+	if !establishmentAccess(ctx, establishmentId) {
+		return
+	}
+
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
+
+	getEstablishmentByID := models.GetEstablishmentByID
+	if includeDeleted {
+		getEstablishmentByID = models.GetEstablishmentByIDWithDeleted
+	}
+
+	establishment, err := getEstablishmentByID(establishmentId)
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi encontrado nenhum estabelecimento com esse ID."})
@@ -113,6 +182,10 @@ func updateEstablishment(ctx *gin.Context) {
 		return
 	}
 
+	if !establishmentAccess(ctx, establishmentId) {
+		return
+	}
+
 	establishment, err := models.GetEstablishmentByID(establishmentId)
 
 	if err != nil {
@@ -130,8 +203,12 @@ func updateEstablishment(ctx *gin.Context) {
 	}
 
 	updatedEstablishment.ID = establishment.ID
+	updatedEstablishment.CreatedAt = establishment.CreatedAt
 	updatedEstablishment.UpdatedAt = time.Now()
 	updatedEstablishment.EnderecoID = establishment.EnderecoID
+	if updatedEstablishment.MetodoCusteio == "" {
+		updatedEstablishment.MetodoCusteio = establishment.MetodoCusteio
+	}
 	updatedEstablishment.Endereco.UpdatedAt = updatedEstablishment.UpdatedAt
 
 	exists, err := utils.CpfCnpjExistsExcludingEc(updatedEstablishment.CPFCNPJ, updatedEstablishment.ID)
@@ -168,6 +245,14 @@ func updateEstablishment(ctx *gin.Context) {
 		return
 	}
 
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditEstablishment, updatedEstablishment.ID, updatedEstablishment.ID, models.AuditUpdate, establishment, updatedEstablishment)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o estabelecimento. Falha ao registrar a auditoria."})
+		return
+	}
+
 	err = tx.Commit()
 
 	if err != nil {
@@ -187,6 +272,10 @@ func deleteEstablishment(ctx *gin.Context) {
 		return
 	}
 
+	if !establishmentAccess(ctx, establishmentId) {
+		return
+	}
+
 	establishment, err := models.GetEstablishmentByID(establishmentId)
 
 	if err != nil {
@@ -212,15 +301,11 @@ func deleteEstablishment(ctx *gin.Context) {
 		return
 	}
 
-	var deletedAddress models.Address
-
-	deletedAddress.ID = establishment.EnderecoID
-
-	err = deletedAddress.Delete(tx)
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditEstablishment, establishment.ID, establishment.ID, models.AuditDelete, establishment, nil)
 
 	if err != nil {
 		tx.Rollback()
-		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível deletar o estabelecimento. Erro ao deletar o endereço"})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o estabelecimento. Falha ao registrar a auditoria."})
 		return
 	}
 
@@ -234,3 +319,120 @@ func deleteEstablishment(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "Estabelecimento deletado com sucesso."})
 
 }
+
+func restoreEstablishment(ctx *gin.Context) {
+	establishmentId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	if !establishmentAccess(ctx, establishmentId) {
+		return
+	}
+
+	establishment, err := models.GetEstablishmentByIDWithDeleted(establishmentId)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi encontrado nenhum estabelecimento com esse ID."})
+		return
+	}
+
+	if establishment.DeletedAt == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O estabelecimento não está excluído."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o estabelecimento. Falha interna."})
+		return
+	}
+
+	err = establishment.Restore(tx)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível restaurar o estabelecimento."})
+		return
+	}
+
+	restoredEstablishment := *establishment
+	restoredEstablishment.DeletedAt = nil
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditEstablishment, establishment.ID, establishment.ID, models.AuditRestore, establishment, restoredEstablishment)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o estabelecimento. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o estabelecimento. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, restoredEstablishment)
+}
+
+// purgeEstablishment remove definitivamente um estabelecimento já excluído,
+// levando junto seu endereço e, em cascata, seus usuários e produtos.
+func purgeEstablishment(ctx *gin.Context) {
+	establishmentId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	if !establishmentAccess(ctx, establishmentId) {
+		return
+	}
+
+	establishment, err := models.GetEstablishmentByIDWithDeleted(establishmentId)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi encontrado nenhum estabelecimento com esse ID."})
+		return
+	}
+
+	if establishment.DeletedAt == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O estabelecimento precisa ser excluído antes de ser expurgado."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o estabelecimento. Falha interna."})
+		return
+	}
+
+	err = establishment.Purge(tx)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível expurgar o estabelecimento."})
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditEstablishment, establishment.ID, establishment.ID, models.AuditPurge, establishment, nil)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o estabelecimento. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o estabelecimento. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Estabelecimento expurgado com sucesso."})
+}
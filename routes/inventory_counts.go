@@ -0,0 +1,272 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+type inventoryCountSubmission struct {
+	Itens []models.InventoryCountEntry `json:"itens" binding:"required,min=1,dive"`
+}
+
+func inventoryCountErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para essa operação nesta contagem de inventário."})
+	case errors.Is(err, models.ErrInventoryCountAlreadyOpen):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Já existe uma contagem de inventário aberta para este estabelecimento."})
+	case errors.Is(err, models.ErrInvalidInventoryCountStatus):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "A contagem de inventário não está aberta."})
+	case errors.Is(err, models.ErrInvalidInventoryCountItem):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "SKU não encontrado entre os produtos desta contagem."})
+	case errors.Is(err, models.ErrFractionalQuantity):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de estoque de um dos produtos não aceita quantidades fracionadas."})
+	case errors.Is(err, models.ErrInsufficientStock):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "O ajuste deixaria o estoque de um ou mais produtos negativo."})
+	case errors.Is(err, models.ErrParentProductStock):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Um produto da contagem passou a ter variantes. Conte as variantes e zere o produto pai."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Contagem de inventário ou estabelecimento não encontrado."})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar a contagem de inventário."})
+	}
+}
+
+func createInventoryCount(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	var count models.InventoryCount
+
+	if ctx.Request.ContentLength != 0 {
+		err := ctx.ShouldBindJSON(&count)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição inválida."})
+			return
+		}
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao abrir a contagem de inventário. Falha interna."})
+		return
+	}
+
+	err = count.Save(tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		inventoryCountErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao abrir a contagem de inventário. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, count)
+}
+
+func getInventoryCounts(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.InventoryCountFilter{
+		Status: ctx.Query("status"),
+	}
+
+	params, err := parseListParams(ctx, models.InventoryCountSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	counts, result, err := models.GetAllInventoryCounts(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as contagens de inventário."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, counts, params, result))
+}
+
+func getInventoryCount(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	countId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	count, err := models.GetInventoryCount(countId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Contagem de inventário não encontrada."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, count)
+}
+
+func getInventoryCountReport(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	countId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	count, err := models.GetInventoryCount(countId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Contagem de inventário não encontrada."})
+		return
+	}
+
+	report, err := count.Report()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível gerar o relatório de divergências."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// submitInventoryCount recebe as quantidades contadas. Não devolve o estoque
+// esperado para não influenciar quem está contando.
+func submitInventoryCount(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	countId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	var input inventoryCountSubmission
+
+	err = ctx.ShouldBindJSON(&input)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe ao menos um item com sku e quantidade."})
+		return
+	}
+
+	count := models.InventoryCount{ID: countId}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao registrar a contagem. Falha interna."})
+		return
+	}
+
+	err = count.Submit(tx, role, userIdRaw.(int64), input.Itens)
+
+	if err != nil {
+		tx.Rollback()
+		inventoryCountErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao registrar a contagem. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Contagem registrada com sucesso.", "itens": input.Itens})
+}
+
+func inventoryCountAction(ctx *gin.Context, apply func(c *models.InventoryCount, tx *sql.Tx, role string, userId int64) error) *models.InventoryCount {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	countId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return nil
+	}
+
+	count := models.InventoryCount{ID: countId}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a contagem de inventário. Falha interna."})
+		return nil
+	}
+
+	err = apply(&count, tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		inventoryCountErrorResponse(ctx, err)
+		return nil
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a contagem de inventário. Falha interna."})
+		return nil
+	}
+
+	return &count
+}
+
+func closeInventoryCount(ctx *gin.Context) {
+	count := inventoryCountAction(ctx, func(c *models.InventoryCount, tx *sql.Tx, role string, userId int64) error {
+		return c.Close(tx, role, userId)
+	})
+
+	if count == nil {
+		return
+	}
+
+	report, err := count.Report()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Contagem fechada, mas não foi possível gerar o relatório de divergências."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+func cancelInventoryCount(ctx *gin.Context) {
+	count := inventoryCountAction(ctx, func(c *models.InventoryCount, tx *sql.Tx, role string, userId int64) error {
+		return c.Cancel(tx, role, userId)
+	})
+
+	if count == nil {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, count)
+}
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+func createInvitation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	var invitation models.Invitation
+
+	err := ctx.ShouldBindJSON(&invitation)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe email e role (OWNER, MANAGER ou SELLER)."})
+		return
+	}
+
+	exists, err := utils.EmailExists(invitation.Email)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar Email"})
+		return
+	}
+
+	if exists {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Esse email já está sendo utilizado."})
+		return
+	}
+
+	err = invitation.Save(role, userIdRaw.(int64))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvitationRole):
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "Apenas um OWNER pode convidar outro OWNER."})
+		case errors.Is(err, models.ErrAccessDenied):
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "Você só pode convidar usuários para o seu estabelecimento."})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível criar o convite."})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, invitation)
+}
+
+func getInvitations(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	invitations, err := models.GetAllInvitations(role, userIdStr)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os convites."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invitations)
+}
+
+func acceptInvitation(ctx *gin.Context) {
+	var input models.AcceptInvitationInput
+
+	err := ctx.ShouldBindJSON(&input)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe token, nome, sobrenome e password."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Tente novamente mais tarde."})
+		return
+	}
+
+	user, err := input.Accept(tx)
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, models.ErrInvalidInvitation):
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Convite não encontrado."})
+		case errors.Is(err, models.ErrInvitationExpired):
+			ctx.JSON(http.StatusGone, gin.H{"message": "O convite expirou."})
+		case errors.Is(err, models.ErrInvitationAccepted):
+			ctx.JSON(http.StatusConflict, gin.H{"message": "O convite já foi utilizado."})
+		default:
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				ctx.JSON(http.StatusConflict, gin.H{"message": "Esse email já está sendo utilizado."})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Tente novamente mais tarde."})
+		}
+		return
+	}
+
+	actor := models.Actor{UserID: user.ID, Role: user.Role}
+
+	err = models.RecordAudit(tx, actor, models.AuditUser, user.ID, user.EstabelecimentoID, models.AuditCreate, nil, user.Public())
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Tente novamente mais tarde."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Cadastro realizado com sucesso",
+		"user": gin.H{
+			"id":                 user.ID,
+			"email":              user.Email,
+			"role":               user.Role,
+			"estabelecimento_id": user.EstabelecimentoID,
+		},
+	})
+}
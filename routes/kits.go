@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+type kitComponentsInput struct {
+	Componentes []models.KitComponent `json:"componentes" binding:"required,min=1,dive"`
+}
+
+func getKitComponents(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	if product.Tipo != models.ProductKit {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O produto não é um kit."})
+		return
+	}
+
+	components, err := models.GetKitComponents(product.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os componentes do kit."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"estoque_disponivel": product.Estoque, "componentes": components})
+}
+
+// setKitComponents substitui a lista de materiais do kit.
+func setKitComponents(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	var input kitComponentsInput
+
+	err = ctx.ShouldBindJSON(&input)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe ao menos um componente com component_id e quantidade."})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao salvar os componentes do kit. Falha interna."})
+		return
+	}
+
+	err = models.SetKitComponents(tx, product.ID, input.Componentes)
+
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, models.ErrNotKit):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "O produto não é um kit."})
+		case errors.Is(err, models.ErrInvalidKitComponent):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Componente inválido. Os componentes devem ser produtos ativos do mesmo estabelecimento, sem variantes e sem repetição."})
+		case errors.Is(err, models.ErrCircularKit):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Um dos componentes contém o próprio kit."})
+		case errors.Is(err, models.ErrFractionalQuantity):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de um dos componentes não aceita quantidades fracionadas."})
+		case errors.Is(err, models.ErrInvalidQuantity):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A quantidade de cada componente deve ser maior que zero."})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível salvar os componentes do kit."})
+		}
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao salvar os componentes do kit. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"componentes": input.Componentes})
+}
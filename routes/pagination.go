@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+// parseListParams lê page, per_page, after e sort da query string. Os campos
+// de ordenação aceitos são os da whitelist de cada recurso.
+func parseListParams(ctx *gin.Context, sortColumns map[string]string) (models.ListParams, error) {
+	params := models.ListParams{Page: 1, PerPage: models.DefaultPerPage}
+
+	if page := ctx.Query("page"); page != "" {
+		value, err := strconv.Atoi(page)
+		if err != nil || value < 1 {
+			return params, errors.New("o parâmetro page deve ser um número inteiro maior que zero")
+		}
+		params.Page = value
+	}
+
+	if perPage := ctx.Query("per_page"); perPage != "" {
+		value, err := strconv.Atoi(perPage)
+		if err != nil || value < 1 || value > models.MaxPerPage {
+			return params, errors.New("o parâmetro per_page deve estar entre 1 e 100")
+		}
+		params.PerPage = value
+	}
+
+	sort, err := models.ParseSort(ctx.Query("sort"), sortColumns)
+	if err != nil {
+		return params, err
+	}
+	params.Sort = sort
+
+	if after := ctx.Query("after"); after != "" {
+		if len(params.Sort) > 0 {
+			return params, models.ErrCursorWithSort
+		}
+
+		id, err := models.DecodeCursor(after)
+		if err != nil {
+			return params, err
+		}
+		params.After = id
+	}
+
+	return params, nil
+}
+
+func listLink(ctx *gin.Context, set map[string]string, remove ...string) string {
+	url := *ctx.Request.URL
+	query := url.Query()
+
+	for _, key := range remove {
+		query.Del(key)
+	}
+
+	for key, value := range set {
+		query.Set(key, value)
+	}
+
+	url.RawQuery = query.Encode()
+
+	return url.String()
+}
+
+// listResponse monta o envelope padrão das listagens com total, cursor da
+// próxima página e links de navegação.
+func listResponse(ctx *gin.Context, data interface{}, params models.ListParams, result models.ListResult) gin.H {
+	links := gin.H{"self": ctx.Request.URL.String()}
+
+	if result.NextCursor != "" {
+		links["next"] = listLink(ctx, map[string]string{"after": result.NextCursor}, "page")
+	} else if result.HasMore {
+		links["next"] = listLink(ctx, map[string]string{"page": strconv.Itoa(params.Page + 1)}, "after")
+	}
+
+	if params.After == 0 && params.Page > 1 {
+		links["prev"] = listLink(ctx, map[string]string{"page": strconv.Itoa(params.Page - 1)})
+	}
+
+	response := gin.H{
+		"data":        data,
+		"total":       result.Total,
+		"per_page":    params.PerPage,
+		"next_cursor": result.NextCursor,
+		"links":       links,
+	}
+
+	if params.After == 0 {
+		response["page"] = params.Page
+	}
+
+	return response
+}
+
+func listParamsError(ctx *gin.Context, err error) {
+	ctx.JSON(http.StatusBadRequest, gin.H{"message": "Parâmetros de paginação inválidos.", "error": err.Error()})
+}
+
+// includeDeletedParam lê ?incluir_excluidos=true, permitido apenas para o
+// OWNER. Quando o acesso é negado a resposta já foi escrita e ok é false.
+func includeDeletedParam(ctx *gin.Context) (include bool, ok bool) {
+	if ctx.Query("incluir_excluidos") != "true" {
+		return false, true
+	}
+
+	if ctx.GetString("role") != "OWNER" {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Apenas o OWNER pode consultar registros excluídos."})
+		return false, false
+	}
+
+	return true, true
+}
@@ -0,0 +1,378 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+func priceListErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para alterar listas de preço deste estabelecimento."})
+	case errors.Is(err, models.ErrInvalidPriceListItem):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Item inválido. Informe valor ou ajuste_percentual (apenas um) para um produto do estabelecimento da lista."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Lista de preços ou item não encontrado."})
+	default:
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Já existe uma lista de preços com esse nome no estabelecimento."})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar a lista de preços."})
+	}
+}
+
+func createPriceList(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	var list models.PriceList
+
+	err := ctx.ShouldBindJSON(&list)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe nome e estabelecimento_id. O ajuste_percentual não pode ser menor que -100."})
+		return
+	}
+
+	list.Itens = nil
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar a lista de preços. Falha interna."})
+		return
+	}
+
+	err = list.Save(tx, role, userIdStr)
+
+	if err != nil {
+		tx.Rollback()
+		priceListErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar a lista de preços. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, list)
+}
+
+func getPriceLists(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.PriceListFilter{
+		Nome: ctx.Query("nome"),
+	}
+
+	params, err := parseListParams(ctx, models.PriceListSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	lists, result, err := models.GetAllPriceLists(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as listas de preço."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, lists, params, result))
+}
+
+func getPriceList(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	listId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	list, err := models.GetPriceList(listId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Lista de preços não encontrada."})
+		return
+	}
+
+	err = list.LoadItems()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível carregar os itens da lista de preços."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+func updatePriceList(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	listId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	list, err := models.GetPriceList(listId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Lista de preços não encontrada."})
+		return
+	}
+
+	var updatedList models.PriceList
+
+	err = ctx.ShouldBindJSON(&updatedList)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Erro na requisição. Verifique os parametros obrigatórios e tente novamente."})
+		return
+	}
+
+	updatedList.ID = list.ID
+	updatedList.EstabelecimentoID = list.EstabelecimentoID
+	updatedList.Itens = nil
+	updatedList.CreatedAt = list.CreatedAt
+	updatedList.UpdatedAt = time.Now()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a lista de preços. Falha interna."})
+		return
+	}
+
+	err = updatedList.Update(tx)
+
+	if err != nil {
+		tx.Rollback()
+		priceListErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a lista de preços. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedList)
+}
+
+func deletePriceList(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	listId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	list, err := models.GetPriceList(listId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Lista de preços não encontrada."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar a lista de preços. Falha interna."})
+		return
+	}
+
+	err = list.Delete(tx)
+
+	if err != nil {
+		tx.Rollback()
+		priceListErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar a lista de preços. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Lista de preços deletada com sucesso."})
+}
+
+func setPriceListItem(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	listId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	list, err := models.GetPriceList(listId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Lista de preços não encontrada."})
+		return
+	}
+
+	var item models.PriceListItem
+
+	err = ctx.ShouldBindJSON(&item)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe product_id, valor ou ajuste_percentual e, para faixas de quantidade, quantidade_minima."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao salvar o item da lista de preços. Falha interna."})
+		return
+	}
+
+	err = item.Save(tx, list)
+
+	if err != nil {
+		tx.Rollback()
+		priceListErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao salvar o item da lista de preços. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item)
+}
+
+func deletePriceListItem(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	listId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	itemId, err := strconv.ParseInt(ctx.Param("itemId"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id do item"})
+		return
+	}
+
+	list, err := models.GetPriceList(listId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Lista de preços não encontrada."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao remover o item da lista de preços. Falha interna."})
+		return
+	}
+
+	err = models.DeletePriceListItem(tx, list.ID, itemId)
+
+	if err != nil {
+		tx.Rollback()
+		priceListErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao remover o item da lista de preços. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Item removido da lista de preços com sucesso."})
+}
+
+// resolveProductPrice informa o preço unitário do produto para a lista (nome ou
+// id) e a quantidade, ambos opcionais. A quantidade está na unidade de
+// estoque do produto.
+func resolveProductPrice(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	quantidade := 1.0
+	if raw := ctx.Query("quantidade"); raw != "" {
+		quantidade, err = strconv.ParseFloat(raw, 64)
+
+		if err != nil || quantidade <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A quantidade deve ser um número maior que zero."})
+			return
+		}
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	resolution, err := models.ResolveProductPrice(product, ctx.Query("lista"), quantidade)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Lista de preços não encontrada no estabelecimento do produto."})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível calcular o preço do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resolution)
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func getProductLots(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	lots, err := models.GetProductLots(product.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os lotes do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lots)
+}
+
+func getExpiringLots(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	dias, err := strconv.Atoi(ctx.DefaultQuery("dias", "30"))
+
+	if err != nil || dias < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O parâmetro dias deve ser um número inteiro positivo."})
+		return
+	}
+
+	lots, err := models.GetExpiringLots(role, userIdStr, dias)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os lotes próximos do vencimento."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, lots)
+}
@@ -0,0 +1,205 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// getProductPrices lista o histórico e as mudanças agendadas do preço do
+// produto. Com data (DD/MM/AAAA), informa também o preço em vigor ao fim
+// daquele dia.
+func getProductPrices(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	response := gin.H{"valor": product.Valor}
+
+	if raw := ctx.Query("data"); raw != "" {
+		data, err := time.Parse("02/01/2006", raw)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data inválida. Utilize o formato DD/MM/AAAA."})
+			return
+		}
+
+		price, err := models.GetProductPriceAt(product.ID, data)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "O produto não tinha preço nessa data."})
+			return
+		}
+
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível consultar o preço na data."})
+			return
+		}
+
+		response["preco_na_data"] = price
+	}
+
+	prices, err := models.GetProductPrices(product.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os preços do produto."})
+		return
+	}
+
+	historico := []models.ProductPrice{}
+	agendados := []models.ProductPrice{}
+	for _, price := range prices {
+		if price.AplicadoEm == nil {
+			agendados = append(agendados, price)
+		} else {
+			historico = append(historico, price)
+		}
+	}
+
+	response["historico"] = historico
+	response["agendados"] = agendados
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func scheduleProductPrice(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	var schedule models.PriceSchedule
+
+	err = ctx.ShouldBindJSON(&schedule)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe valor e vigente_de (DD/MM/AAAA ou DD/MM/AAAA HH:MM)."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao agendar o preço. Falha interna."})
+		return
+	}
+
+	price, err := models.SchedulePrice(tx, product.ID, schedule, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, models.ErrInvalidPriceDate) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A data de vigência deve ser futura, no formato DD/MM/AAAA ou DD/MM/AAAA HH:MM."})
+			return
+		}
+
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Já existe uma mudança de preço agendada para essa data."})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível agendar o preço."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao agendar o preço. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, price)
+}
+
+func cancelScheduledProductPrice(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	priceId, err := strconv.ParseInt(ctx.Param("priceId"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id do preço"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cancelar o preço agendado. Falha interna."})
+		return
+	}
+
+	err = models.CancelScheduledPrice(tx, product.ID, priceId)
+
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, models.ErrPriceAlreadyApplied):
+			ctx.JSON(http.StatusConflict, gin.H{"message": "A mudança de preço já foi aplicada e faz parte do histórico."})
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Preço agendado não encontrado."})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cancelar o preço agendado."})
+		}
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cancelar o preço agendado. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Preço agendado cancelado com sucesso."})
+}
@@ -0,0 +1,124 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+type productAttributesInput struct {
+	Atributos []models.ProductAttribute `json:"atributos" binding:"dive"`
+}
+
+// variantErrorResponse responde aos erros de variantes. Retorna false quando o
+// erro não é de variante e a resposta ainda precisa ser escrita.
+func variantErrorResponse(ctx *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, models.ErrInvalidVariant):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O produto pai deve existir no mesmo estabelecimento e não pode ser uma variante."})
+	case errors.Is(err, models.ErrInvalidVariantAttributes):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Os atributos devem corresponder às definições do produto pai, com um valor permitido para cada atributo."})
+	case errors.Is(err, models.ErrDuplicateVariant):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Já existe uma variante com essa combinação de atributos."})
+	case errors.Is(err, models.ErrParentProductStock):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Produtos com variantes não têm estoque próprio. Zere o estoque do produto pai e movimente o das variantes."})
+	case errors.Is(err, models.ErrAttributeInUse):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "As novas definições não atendem às variantes já cadastradas."})
+	default:
+		return false
+	}
+
+	return true
+}
+
+func getProductAttributes(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	attributes, err := models.GetProductAttributes(product.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os atributos do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attributes)
+}
+
+// setProductAttributes substitui as definições de atributos (ex.: Tamanho,
+// Cor) usadas pelas variantes do produto.
+func setProductAttributes(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	var input productAttributesInput
+
+	err = ctx.ShouldBindJSON(&input)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição inválida. Informe atributos com nome e, opcionalmente, a lista de valores."})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao salvar os atributos. Falha interna."})
+		return
+	}
+
+	err = models.SetProductAttributes(tx, product.ID, input.Atributos)
+
+	if err != nil {
+		tx.Rollback()
+		if variantErrorResponse(ctx, err) {
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível salvar os atributos do produto."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao salvar os atributos. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, input.Atributos)
+}
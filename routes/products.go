@@ -1,17 +1,22 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
 	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
 	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
 	"github.com/gin-gonic/gin"
 )
 
 func createProduct(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userId := userIdRaw.(int64)
+
 	var product models.Product
 
 	err := ctx.ShouldBindJSON(&product)
@@ -23,7 +28,7 @@ func createProduct(ctx *gin.Context) {
 		return
 	}
 
-	exists, err := utils.SKUExists(product.SKU)
+	exists, err := utils.SKUExists(product.SKU, product.EstabelecimentoID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar SKU"})
 		return
@@ -34,15 +39,57 @@ func createProduct(ctx *gin.Context) {
 		return
 	}
 
-	err = product.Save()
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o produto. Falha interna."})
+		return
+	}
+
+	err = product.Save(tx, userId)
 
 	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, models.ErrInvalidQuantity) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "O estoque inicial não pode ser negativo."})
+			return
+		}
+		if errors.Is(err, models.ErrFractionalQuantity) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de estoque do produto não aceita estoque inicial fracionado."})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidUnit) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Unidade de medida inválida. Use UN, KG, L ou CX, com fator maior que zero e que resulte em quantidade inteira quando a unidade de estoque for inteira."})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidCategory) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Categoria não encontrada no estabelecimento do produto."})
+			return
+		}
+		if variantErrorResponse(ctx, err) || barcodeErrorResponse(ctx, err) {
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"message": "Não foi possível cadastrar o produto. Tente novamente mais tarde.",
 		})
 		return
 	}
 
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditProduct, product.ID, product.EstabelecimentoID, models.AuditCreate, nil, product)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o produto. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o produto. Falha interna."})
+		return
+	}
+
 	ctx.JSON(http.StatusCreated, product)
 }
 
@@ -57,16 +104,33 @@ func getProducts(ctx *gin.Context) {
 		Valor:       ctx.Query("valor"),
 		StartDate:   ctx.Query("data_inicial"),
 		EndDate:     ctx.Query("data_final"),
+		CategoryID:  ctx.Query("categoria_id"),
+		ParentID:    ctx.Query("parent_id"),
 	}
 
-	products, err := models.GetAllProducts(role, userIdStr, filters)
+	filters.GroupVariants, _ = strconv.ParseBool(ctx.Query("agrupar_variantes"))
+
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
+	filters.IncludeDeleted = includeDeleted
+
+	params, err := parseListParams(ctx, models.ProductSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	products, result, err := models.GetAllProducts(role, userIdStr, filters, params)
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possivel listar os produtos.", "error": err})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, products)
+	ctx.JSON(http.StatusOK, listResponse(ctx, products, params, result))
 }
 
 func getProductById(ctx *gin.Context) {
@@ -81,13 +145,39 @@ func getProductById(ctx *gin.Context) {
 		return
 	}
 
-	product, err := models.GetProduct(productId, role, userIdStr)
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
+
+	getProduct := models.GetProduct
+	if includeDeleted {
+		getProduct = models.GetProductWithDeleted
+	}
+
+	product, err := getProduct(productId, role, userIdStr)
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Produto não encontrado."})
 		return
 	}
 
+	if product.ParentID == nil {
+		err = product.LoadVariants()
+
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível carregar as variantes do produto."})
+			return
+		}
+	}
+
+	err = product.LoadLocations()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível carregar os locais do produto."})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, product)
 }
 
@@ -120,9 +210,15 @@ func updateProduct(ctx *gin.Context) {
 	}
 
 	updatedProduct.ID = product.ID
+	updatedProduct.CreatedAt = product.CreatedAt
+	updatedProduct.EstoqueAvariado = product.EstoqueAvariado
 	updatedProduct.UpdatedAt = time.Now()
 
-	exists, err := utils.SKUExistsForOtherProduct(updatedProduct.SKU, updatedProduct.ID)
+	if !models.IsGlobalOwner(role, userIdStr) {
+		updatedProduct.EstabelecimentoID = product.EstabelecimentoID
+	}
+
+	exists, err := utils.SKUExistsForOtherProduct(updatedProduct.SKU, updatedProduct.ID, updatedProduct.EstabelecimentoID)
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar SKU"})
@@ -134,13 +230,55 @@ func updateProduct(ctx *gin.Context) {
 		return
 	}
 
-	err = updatedProduct.Update(role)
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o produto. Falha interna."})
+		return
+	}
+
+	err = updatedProduct.Update(tx, role, userIdRaw.(int64))
 
 	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, models.ErrInsufficientStock) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "O estoque não pode ficar negativo."})
+			return
+		}
+		if errors.Is(err, models.ErrFractionalQuantity) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de estoque do produto não aceita estoque fracionado."})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidUnit) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Unidade de medida inválida. Use UN, KG, L ou CX, com fator maior que zero, e só troque para uma unidade inteira com estoque inteiro."})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidCategory) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Categoria não encontrada no estabelecimento do produto."})
+			return
+		}
+		if variantErrorResponse(ctx, err) || barcodeErrorResponse(ctx, err) {
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possivel atualizar o produto"})
 		return
 	}
 
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditProduct, updatedProduct.ID, updatedProduct.EstabelecimentoID, models.AuditUpdate, product, updatedProduct)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o produto. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o produto. Falha interna."})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"message": "Produto atualizado com sucesso",
 		"produto": updatedProduct,
@@ -170,12 +308,150 @@ func deleteProduct(ctx *gin.Context) {
 
 	deletedProduct.ID = product.ID
 
-	err = deletedProduct.Delete()
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o produto. Falha interna."})
+		return
+	}
+
+	err = deletedProduct.Delete(tx)
 
 	if err != nil {
+		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possivel deletar o produto"})
 		return
 	}
 
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditProduct, product.ID, product.EstabelecimentoID, models.AuditDelete, product, nil)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o produto. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o produto. Falha interna."})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Produto deletado com sucesso"})
 }
+
+func restoreProduct(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProductWithDeleted(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	if product.DeletedAt == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O produto não está excluído."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o produto. Falha interna."})
+		return
+	}
+
+	err = product.Restore(tx)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível restaurar o produto."})
+		return
+	}
+
+	restoredProduct := *product
+	restoredProduct.DeletedAt = nil
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditProduct, product.ID, product.EstabelecimentoID, models.AuditRestore, product, restoredProduct)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o produto. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o produto. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Produto restaurado com sucesso", "produto": restoredProduct})
+}
+
+// purgeProduct remove definitivamente um produto que já foi excluído.
+func purgeProduct(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProductWithDeleted(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	if product.DeletedAt == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O produto precisa ser excluído antes de ser expurgado."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o produto. Falha interna."})
+		return
+	}
+
+	err = product.Purge(tx)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível expurgar o produto."})
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditProduct, product.ID, product.EstabelecimentoID, models.AuditPurge, product, nil)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o produto. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o produto. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Produto expurgado com sucesso"})
+}
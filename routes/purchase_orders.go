@@ -0,0 +1,220 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func purchaseOrderErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para essa operação neste pedido de compra."})
+	case errors.Is(err, models.ErrPurchaseApprovalRequired):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": fmt.Sprintf("Pedidos acima de %.2f precisam ser aprovados por um OWNER antes do envio.", models.PurchaseApprovalLimit())})
+	case errors.Is(err, models.ErrInvalidPurchaseOrderStatus):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "O pedido de compra não está em um status que permita essa operação."})
+	case errors.Is(err, models.ErrInvalidPurchaseOrderItem):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Item inválido. Os produtos devem existir no estabelecimento do fornecedor e não podem se repetir no pedido."})
+	case errors.Is(err, models.ErrReceiptExceedsOrdered):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A quantidade recebida excede a quantidade pendente do item."})
+	case errors.Is(err, models.ErrParentProductStock):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Produtos com variantes não recebem estoque. Peça as variantes."})
+	case errors.Is(err, models.ErrSupplierEstablishmentMismatch):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O fornecedor não pertence ao estabelecimento do pedido."})
+	case errors.Is(err, models.ErrFractionalQuantity):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de compra de um dos itens não aceita quantidades fracionadas."})
+	case errors.Is(err, models.ErrInvalidLocation):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Local de armazenagem inválido para o estabelecimento do pedido."})
+	case errors.Is(err, models.ErrInvalidQuantity), errors.Is(err, models.ErrInvalidExpiryDate):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Quantidade ou validade inválida no recebimento."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Fornecedor não encontrado."})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar o pedido de compra."})
+	}
+}
+
+func createPurchaseOrder(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	var order models.PurchaseOrder
+
+	err := ctx.ShouldBindJSON(&order)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe supplier_id e ao menos um item com product_id, quantidade e custo_unitario."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao criar o pedido de compra. Falha interna."})
+		return
+	}
+
+	err = order.Save(tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		purchaseOrderErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao criar o pedido de compra. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, order)
+}
+
+func getPurchaseOrders(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.PurchaseOrderFilter{
+		Status:     ctx.Query("status"),
+		SupplierID: ctx.Query("supplier_id"),
+	}
+
+	params, err := parseListParams(ctx, models.PurchaseOrderSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	orders, result, err := models.GetAllPurchaseOrders(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os pedidos de compra."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, orders, params, result))
+}
+
+func getPurchaseOrder(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	orderId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	order, err := models.GetPurchaseOrder(orderId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Pedido de compra não encontrado."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+// purchaseOrderAction carrega o pedido e executa uma operação sobre ele em
+// uma única transação.
+func purchaseOrderAction(ctx *gin.Context, apply func(po *models.PurchaseOrder, tx *sql.Tx, role string, userId int64) error) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	orderId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	order, err := models.GetPurchaseOrder(orderId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Pedido de compra não encontrado."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o pedido de compra. Falha interna."})
+		return
+	}
+
+	err = apply(order, tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		purchaseOrderErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o pedido de compra. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func updatePurchaseOrder(ctx *gin.Context) {
+	var input models.PurchaseOrder
+
+	err := ctx.ShouldBindJSON(&input)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe supplier_id e ao menos um item com product_id, quantidade e custo_unitario."})
+		return
+	}
+
+	purchaseOrderAction(ctx, func(po *models.PurchaseOrder, tx *sql.Tx, role string, userId int64) error {
+		po.SupplierID = input.SupplierID
+		po.Observacao = input.Observacao
+		po.Itens = input.Itens
+		return po.Update(tx, role, userId)
+	})
+}
+
+func approvePurchaseOrder(ctx *gin.Context) {
+	purchaseOrderAction(ctx, (*models.PurchaseOrder).Approve)
+}
+
+func sendPurchaseOrder(ctx *gin.Context) {
+	purchaseOrderAction(ctx, (*models.PurchaseOrder).Send)
+}
+
+func receivePurchaseOrder(ctx *gin.Context) {
+	var receipt models.PurchaseReceipt
+
+	if ctx.Request.ContentLength != 0 {
+		err := ctx.ShouldBindJSON(&receipt)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição inválida. Cada item recebido precisa de product_id e quantidade maior que zero."})
+			return
+		}
+	}
+
+	purchaseOrderAction(ctx, func(po *models.PurchaseOrder, tx *sql.Tx, role string, userId int64) error {
+		return po.Receive(tx, role, userId, receipt)
+	})
+}
+
+func cancelPurchaseOrder(ctx *gin.Context) {
+	purchaseOrderAction(ctx, (*models.PurchaseOrder).Cancel)
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+// getInventoryValuation avalia o estoque ao fim do dia informado em data
+// (DD/MM/AAAA), ou de hoje quando data não é informada.
+func getInventoryValuation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	now := time.Now()
+	data := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if raw := ctx.Query("data"); raw != "" {
+		var err error
+		data, err = time.Parse("02/01/2006", raw)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data inválida. Utilize o formato DD/MM/AAAA."})
+			return
+		}
+	}
+
+	var estabelecimentoId int64
+	if raw := ctx.Query("estabelecimento_id"); raw != "" {
+		var err error
+		estabelecimentoId, err = strconv.ParseInt(raw, 10, 64)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o estabelecimento_id"})
+			return
+		}
+	}
+
+	valuation, err := models.GetInventoryValuation(role, userIdStr, estabelecimentoId, data)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível avaliar o estoque."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, valuation)
+}
@@ -0,0 +1,245 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func returnErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para registrar devoluções neste estabelecimento."})
+	case errors.Is(err, models.ErrInvalidReturnItem):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Item inválido. Os produtos devem existir no estabelecimento e, se houver venda, ter sido vendidos nela."})
+	case errors.Is(err, models.ErrReturnExceedsSold):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "A quantidade devolvida excede a quantidade vendida ainda não devolvida."})
+	case errors.Is(err, models.ErrParentProductStock):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Produtos com variantes não têm estoque próprio. Movimente o estoque das variantes."})
+	case errors.Is(err, models.ErrFractionalQuantity):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de estoque de um dos produtos não aceita quantidades fracionadas."})
+	case errors.Is(err, models.ErrInvalidSaleStatus):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Não é possível registrar devolução de uma venda cancelada."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Venda não encontrada."})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar a devolução."})
+	}
+}
+
+func createReturn(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	var ret models.Return
+
+	err := ctx.ShouldBindJSON(&ret)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe motivo e ao menos um item com product_id, quantidade e destino (ESTOQUE ou AVARIADO)."})
+		return
+	}
+
+	if ret.DocumentoCliente != "" {
+		formatedDoc, err := utils.FormatAndValidateCpfCnpj(ret.DocumentoCliente)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "CPF ou CNPJ inválido."})
+			return
+		}
+
+		ret.DocumentoCliente = formatedDoc
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao registrar a devolução. Falha interna."})
+		return
+	}
+
+	err = ret.Save(tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		returnErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao registrar a devolução. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, ret)
+}
+
+func getReturns(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.ReturnFilter{
+		SaleID: ctx.Query("sale_id"),
+	}
+
+	if doc := ctx.Query("documento_cliente"); doc != "" {
+		formatedDoc, err := utils.FormatAndValidateCpfCnpj(doc)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "CPF ou CNPJ inválido."})
+			return
+		}
+		filter.DocumentoCliente = formatedDoc
+	}
+
+	params, err := parseListParams(ctx, models.ReturnSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	returns, result, err := models.GetAllReturns(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as devoluções."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, returns, params, result))
+}
+
+func getReturn(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	returnId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	ret, err := models.GetReturn(returnId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Devolução não encontrada."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ret)
+}
+
+func getProductQuarantine(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	movements, err := models.GetQuarantineMovements(product.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar a quarentena do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"estoque_avariado": product.EstoqueAvariado, "movimentacoes": movements})
+}
+
+func writeOffQuarantine(ctx *gin.Context) {
+	changeQuarantine(ctx, models.QuarantineBaixa)
+}
+
+func restockQuarantine(ctx *gin.Context) {
+	changeQuarantine(ctx, models.QuarantineReestoque)
+}
+
+// changeQuarantine retira itens da quarentena de avariados, seja dando baixa
+// definitiva ou devolvendo-os ao estoque vendável.
+func changeQuarantine(ctx *gin.Context, tipo string) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	var movement models.QuarantineMovement
+
+	err = ctx.ShouldBindJSON(&movement)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe quantidade e motivo."})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	movement.ProductID = product.ID
+	movement.Tipo = tipo
+	movement.UserID = userIdRaw.(int64)
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao movimentar a quarentena. Falha interna."})
+		return
+	}
+
+	err = movement.Save(tx)
+
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, models.ErrInsufficientQuarantine) {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Quantidade insuficiente na quarentena de avariados."})
+			return
+		}
+		if errors.Is(err, models.ErrFractionalQuantity) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "O produto não aceita quantidades fracionadas na unidade informada."})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao movimentar a quarentena. Falha interna."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao movimentar a quarentena. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, movement)
+}
@@ -8,22 +8,151 @@ import (
 func RegisterRoutes(server *gin.Engine) {
 	server.POST("/signup", signup)
 	server.POST("/login", login)
+	server.POST("/auth/refresh", refreshToken)
+	server.POST("/invitations/accept", acceptInvitation)
 
 	api := server.Group("/")
 	api.Use(middlewares.AuthMiddleware())
 
+	api.POST("/auth/logout", logout)
+
 	// Usuários
 	api.GET("/users", middlewares.RoleMiddleware("OWNER", "MANAGER"), getUsers)
 	api.GET("/users/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), getUser)
 	api.PUT("/users/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), updateUser)
 	api.DELETE("/users/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), deleteUser)
+	api.POST("/users/:id/restore", middlewares.RoleMiddleware("OWNER", "MANAGER"), restoreUser)
+	api.DELETE("/users/:id/purge", middlewares.RoleMiddleware("OWNER"), purgeUser)
+
+	// Convites
+	api.GET("/invitations", middlewares.RoleMiddleware("OWNER", "MANAGER"), getInvitations)
+	api.POST("/invitations", middlewares.RoleMiddleware("OWNER", "MANAGER"), createInvitation)
 
 	// Produtos
 	api.GET("/products", getProducts)
+	api.GET("/products/low-stock", getLowStockProducts)
+	api.GET("/products/expiring", getExpiringLots)
+	api.GET("/products/barcode/:code", getProductByBarcode)
 	api.GET("/products/:id", getProductById)
 	api.POST("/products", middlewares.RoleMiddleware("OWNER", "MANAGER"), createProduct)
 	api.PUT("/products/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), updateProduct)
 	api.DELETE("/products/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), deleteProduct)
+	api.POST("/products/:id/restore", middlewares.RoleMiddleware("OWNER", "MANAGER"), restoreProduct)
+	api.DELETE("/products/:id/purge", middlewares.RoleMiddleware("OWNER"), purgeProduct)
+	api.GET("/products/:id/attributes", getProductAttributes)
+	api.PUT("/products/:id/attributes", middlewares.RoleMiddleware("OWNER", "MANAGER"), setProductAttributes)
+	api.GET("/products/:id/components", getKitComponents)
+	api.PUT("/products/:id/components", middlewares.RoleMiddleware("OWNER", "MANAGER"), setKitComponents)
+	api.GET("/products/:id/barcodes", getProductBarcodes)
+	api.POST("/products/:id/barcodes", middlewares.RoleMiddleware("OWNER", "MANAGER"), addProductBarcode)
+	api.DELETE("/products/:id/barcodes/:code", middlewares.RoleMiddleware("OWNER", "MANAGER"), deleteProductBarcode)
+	api.GET("/products/:id/prices", getProductPrices)
+	api.POST("/products/:id/prices", middlewares.RoleMiddleware("OWNER", "MANAGER"), scheduleProductPrice)
+	api.DELETE("/products/:id/prices/:priceId", middlewares.RoleMiddleware("OWNER", "MANAGER"), cancelScheduledProductPrice)
+	api.GET("/products/:id/price", resolveProductPrice)
+
+	// Unidades de medida
+	api.GET("/units", getUnits)
+
+	// Categorias
+	api.GET("/categories", getCategories)
+	api.GET("/categories/:id", getCategory)
+	api.POST("/categories", middlewares.RoleMiddleware("OWNER", "MANAGER"), createCategory)
+	api.PUT("/categories/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), updateCategory)
+	api.DELETE("/categories/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), deleteCategory)
+
+	// Fornecedores
+	api.GET("/suppliers", middlewares.RoleMiddleware("OWNER", "MANAGER"), getSuppliers)
+	api.GET("/suppliers/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), getSupplier)
+	api.GET("/suppliers/:id/products", middlewares.RoleMiddleware("OWNER", "MANAGER"), getSupplierProducts)
+	api.POST("/suppliers", middlewares.RoleMiddleware("OWNER", "MANAGER"), createSupplier)
+	api.PUT("/suppliers/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), updateSupplier)
+	api.DELETE("/suppliers/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), deleteSupplier)
+	api.GET("/products/:id/suppliers", middlewares.RoleMiddleware("OWNER", "MANAGER"), getProductSuppliers)
+	api.PUT("/products/:id/suppliers/:supplierId", middlewares.RoleMiddleware("OWNER", "MANAGER"), linkProductSupplier)
+	api.DELETE("/products/:id/suppliers/:supplierId", middlewares.RoleMiddleware("OWNER", "MANAGER"), unlinkProductSupplier)
+
+	// Pedidos de compra
+	api.GET("/purchase-orders", middlewares.RoleMiddleware("OWNER", "MANAGER"), getPurchaseOrders)
+	api.GET("/purchase-orders/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), getPurchaseOrder)
+	api.POST("/purchase-orders", middlewares.RoleMiddleware("OWNER", "MANAGER"), createPurchaseOrder)
+	api.PUT("/purchase-orders/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), updatePurchaseOrder)
+	api.POST("/purchase-orders/:id/approve", middlewares.RoleMiddleware("OWNER"), approvePurchaseOrder)
+	api.POST("/purchase-orders/:id/send", middlewares.RoleMiddleware("OWNER", "MANAGER"), sendPurchaseOrder)
+	api.POST("/purchase-orders/:id/receive", middlewares.RoleMiddleware("OWNER", "MANAGER"), receivePurchaseOrder)
+	api.POST("/purchase-orders/:id/cancel", middlewares.RoleMiddleware("OWNER", "MANAGER"), cancelPurchaseOrder)
+
+	// Vendas
+	api.GET("/sales", getSales)
+	api.GET("/sales/:id", getSale)
+	api.POST("/sales", createSale)
+	api.POST("/sales/:id/cancel", middlewares.RoleMiddleware("OWNER", "MANAGER"), cancelSale)
+
+	// Devoluções
+	api.GET("/returns", middlewares.RoleMiddleware("OWNER", "MANAGER"), getReturns)
+	api.GET("/returns/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), getReturn)
+	api.POST("/returns", middlewares.RoleMiddleware("OWNER", "MANAGER"), createReturn)
+	api.GET("/products/:id/quarantine", middlewares.RoleMiddleware("OWNER", "MANAGER"), getProductQuarantine)
+	api.POST("/products/:id/quarantine/write-off", middlewares.RoleMiddleware("OWNER", "MANAGER"), writeOffQuarantine)
+	api.POST("/products/:id/quarantine/restock", middlewares.RoleMiddleware("OWNER", "MANAGER"), restockQuarantine)
+
+	// Contagens de inventário
+	api.GET("/inventory-counts", middlewares.RoleMiddleware("OWNER", "MANAGER"), getInventoryCounts)
+	api.GET("/inventory-counts/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), getInventoryCount)
+	api.GET("/inventory-counts/:id/report", middlewares.RoleMiddleware("OWNER", "MANAGER"), getInventoryCountReport)
+	api.POST("/inventory-counts", middlewares.RoleMiddleware("OWNER", "MANAGER"), createInventoryCount)
+	api.POST("/inventory-counts/:id/counts", submitInventoryCount)
+	api.POST("/inventory-counts/:id/close", middlewares.RoleMiddleware("OWNER", "MANAGER"), closeInventoryCount)
+	api.POST("/inventory-counts/:id/cancel", middlewares.RoleMiddleware("OWNER", "MANAGER"), cancelInventoryCount)
+
+	// Movimentações de estoque
+	api.GET("/products/:id/movements", getStockMovements)
+	api.POST("/products/:id/movements", middlewares.RoleMiddleware("OWNER", "MANAGER"), createStockMovement)
+
+	// Lotes
+	api.GET("/products/:id/lots", getProductLots)
+
+	// Listas de preço
+	api.GET("/price-lists", getPriceLists)
+	api.GET("/price-lists/:id", getPriceList)
+	api.POST("/price-lists", middlewares.RoleMiddleware("OWNER", "MANAGER"), createPriceList)
+	api.PUT("/price-lists/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), updatePriceList)
+	api.DELETE("/price-lists/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), deletePriceList)
+	api.PUT("/price-lists/:id/items", middlewares.RoleMiddleware("OWNER", "MANAGER"), setPriceListItem)
+	api.DELETE("/price-lists/:id/items/:itemId", middlewares.RoleMiddleware("OWNER", "MANAGER"), deletePriceListItem)
+
+	// Reservas de estoque
+	api.GET("/products/:id/reservations", getProductReservations)
+	api.POST("/products/:id/reservations", createStockReservation)
+	api.GET("/reservations/:id", getStockReservation)
+	api.POST("/reservations/:id/confirm", confirmStockReservation)
+	api.POST("/reservations/:id/release", releaseStockReservation)
+
+	// Locais de armazenagem
+	api.GET("/locations", getStorageLocations)
+	api.GET("/locations/:id", getStorageLocation)
+	api.POST("/locations", middlewares.RoleMiddleware("OWNER", "MANAGER"), createStorageLocation)
+	api.PUT("/locations/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), updateStorageLocation)
+	api.DELETE("/locations/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), deleteStorageLocation)
+	api.GET("/products/:id/location-moves", getProductLocationMoves)
+	api.POST("/products/:id/location-moves", middlewares.RoleMiddleware("OWNER", "MANAGER"), moveProductLocation)
+
+	// Alertas de estoque
+	api.GET("/stock-alerts", middlewares.RoleMiddleware("OWNER", "MANAGER"), getStockAlerts)
+
+	// Transferências
+	api.GET("/transfers", middlewares.RoleMiddleware("OWNER", "MANAGER"), getTransfers)
+	api.GET("/transfers/:id", middlewares.RoleMiddleware("OWNER", "MANAGER"), getTransfer)
+	api.POST("/transfers", middlewares.RoleMiddleware("OWNER", "MANAGER"), createTransfer)
+	api.POST("/transfers/:id/dispatch", middlewares.RoleMiddleware("OWNER", "MANAGER"), dispatchTransfer)
+	api.POST("/transfers/:id/receive", middlewares.RoleMiddleware("OWNER", "MANAGER"), receiveTransfer)
+	api.POST("/transfers/:id/cancel", middlewares.RoleMiddleware("OWNER", "MANAGER"), cancelTransfer)
+
+	// Relatórios
+	api.GET("/reports/inventory-valuation", middlewares.RoleMiddleware("OWNER", "MANAGER"), getInventoryValuation)
+
+	// Auditoria
+	api.GET("/audit", middlewares.RoleMiddleware("OWNER", "MANAGER"), getAuditLog)
 
 	// Estabelecimentos
 	api.POST("/establishments", middlewares.RoleMiddleware("OWNER"), createEstablishment)
@@ -31,4 +160,6 @@ func RegisterRoutes(server *gin.Engine) {
 	api.GET("/establishments/:id", middlewares.RoleMiddleware("OWNER"), getEstablishment)
 	api.PUT("/establishments/:id", middlewares.RoleMiddleware("OWNER"), updateEstablishment)
 	api.DELETE("/establishments/:id", middlewares.RoleMiddleware("OWNER"), deleteEstablishment)
+	api.POST("/establishments/:id/restore", middlewares.RoleMiddleware("OWNER"), restoreEstablishment)
+	api.DELETE("/establishments/:id/purge", middlewares.RoleMiddleware("OWNER"), purgeEstablishment)
 }
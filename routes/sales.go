@@ -0,0 +1,185 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+type cancelSaleInput struct {
+	Motivo string `json:"motivo"`
+}
+
+func saleErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para registrar vendas neste estabelecimento."})
+	case errors.Is(err, models.ErrInsufficientStock):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Estoque disponível insuficiente para um ou mais itens da venda."})
+	case errors.Is(err, models.ErrInvalidSaleItem):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Item inválido. Os produtos devem existir no estabelecimento da venda e não podem se repetir."})
+	case errors.Is(err, models.ErrParentProductStock):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Produtos com variantes não podem ser vendidos diretamente. Informe a variante."})
+	case errors.Is(err, models.ErrInvalidKitComponent):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Um dos kits da venda não tem componentes cadastrados."})
+	case errors.Is(err, models.ErrFractionalQuantity):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de venda de um dos itens não aceita quantidades fracionadas."})
+	case errors.Is(err, models.ErrInvalidDiscount):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O desconto não pode ser maior que o valor do item ou da venda."})
+	case errors.Is(err, models.ErrInvalidSaleStatus):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "A venda já foi cancelada."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Estabelecimento não encontrado."})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar a venda."})
+	}
+}
+
+func createSale(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	var sale models.Sale
+
+	err := ctx.ShouldBindJSON(&sale)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe forma_pagamento e ao menos um item com product_id e quantidade."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao registrar a venda. Falha interna."})
+		return
+	}
+
+	err = sale.Save(tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		saleErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao registrar a venda. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, sale)
+}
+
+func getSales(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.SaleFilter{
+		Status:         ctx.Query("status"),
+		FormaPagamento: ctx.Query("forma_pagamento"),
+		VendedorID:     ctx.Query("vendedor_id"),
+		StartDate:      ctx.Query("data_inicial"),
+		EndDate:        ctx.Query("data_final"),
+	}
+
+	params, err := parseListParams(ctx, models.SaleSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	sales, result, err := models.GetAllSales(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as vendas."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, sales, params, result))
+}
+
+func getSale(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	saleId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	sale, err := models.GetSale(saleId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Venda não encontrada."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sale)
+}
+
+func cancelSale(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	saleId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	var input cancelSaleInput
+
+	if ctx.Request.ContentLength != 0 {
+		err = ctx.ShouldBindJSON(&input)
+
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição inválida."})
+			return
+		}
+	}
+
+	sale, err := models.GetSale(saleId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Venda não encontrada."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cancelar a venda. Falha interna."})
+		return
+	}
+
+	err = sale.Cancel(tx, role, userIdRaw.(int64), input.Motivo)
+
+	if err != nil {
+		tx.Rollback()
+		saleErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cancelar a venda. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, sale)
+}
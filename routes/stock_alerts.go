@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func getLowStockProducts(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	products, err := models.GetLowStockProducts(role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os produtos com estoque baixo."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, products)
+}
+
+func getStockAlerts(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	alerts, err := models.GetStockAlerts(role, userIdStr, ctx.Query("status"))
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os alertas de estoque."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, alerts)
+}
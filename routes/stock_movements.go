@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func createStockMovement(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	var movement models.StockMovement
+
+	err = ctx.ShouldBindJSON(&movement)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe tipo (ENTRADA, SAIDA ou AJUSTE), quantidade e motivo."})
+		return
+	}
+
+	movement.ProductID = product.ID
+	movement.UserID = userIdRaw.(int64)
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao registrar a movimentação. Falha interna."})
+		return
+	}
+
+	err = movement.Save(tx)
+
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, models.ErrInvalidQuantity):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Quantidade inválida. Entradas e saídas devem ser positivas e ajustes diferentes de zero."})
+		case errors.Is(err, models.ErrInsufficientStock):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Estoque insuficiente para realizar a movimentação. Saídas não consomem o estoque reservado."})
+		case errors.Is(err, models.ErrInsufficientLotStock):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Quantidade insuficiente no lote informado."})
+		case errors.Is(err, models.ErrInvalidExpiryDate):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Data de validade inválida. Utilize o formato DD/MM/AAAA."})
+		case errors.Is(err, models.ErrFractionalQuantity):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de estoque do produto não aceita quantidades fracionadas."})
+		case errors.Is(err, models.ErrKitLot):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Kits não têm lotes. Movimente os lotes nos componentes."})
+		case errors.Is(err, models.ErrInvalidKitComponent):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "O kit não tem componentes cadastrados."})
+		case errors.Is(err, models.ErrInvalidLocation):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Local inválido. Informe um local do estabelecimento do produto."})
+		case errors.Is(err, models.ErrInsufficientLocationStock):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Quantidade insuficiente no local informado."})
+		case errors.Is(err, models.ErrParentProductStock):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Produtos com variantes não têm estoque próprio. Movimente o estoque das variantes."})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível registrar a movimentação."})
+		}
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao registrar a movimentação. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, movement)
+}
+
+func getStockMovements(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	movements, err := models.GetProductMovements(product.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as movimentações do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, movements)
+}
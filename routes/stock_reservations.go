@@ -0,0 +1,192 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func reservationErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para reservar estoque deste estabelecimento."})
+	case errors.Is(err, models.ErrInsufficientStock):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Estoque disponível insuficiente para a reserva."})
+	case errors.Is(err, models.ErrInvalidReservationStatus):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "A reserva não está ativa."})
+	case errors.Is(err, models.ErrReservationExpired):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "A reserva expirou. Crie uma nova reserva."})
+	case errors.Is(err, models.ErrKitReservation):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Kits não podem ser reservados. Reserve os componentes."})
+	case errors.Is(err, models.ErrParentProductStock):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Produtos com variantes não têm estoque próprio. Reserve as variantes."})
+	case errors.Is(err, models.ErrFractionalQuantity):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de estoque do produto não aceita quantidades fracionadas."})
+	case errors.Is(err, models.ErrInvalidQuantity):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A quantidade deve ser maior que zero."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Reserva ou produto não encontrado."})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar a reserva."})
+	}
+}
+
+func createStockReservation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	var reservation models.StockReservation
+
+	err = ctx.ShouldBindJSON(&reservation)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe quantidade e, opcionalmente, referencia e ttl_minutos (até 1440)."})
+		return
+	}
+
+	reservation.ProductID = productId
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao reservar o estoque. Falha interna."})
+		return
+	}
+
+	err = reservation.Save(tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		reservationErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao reservar o estoque. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reservation)
+}
+
+func getProductReservations(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	reservations, err := models.GetProductReservations(product.ID, ctx.Query("status"))
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as reservas do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"estoque": product.Estoque, "reservado": product.Reservado, "disponivel": product.Disponivel, "reservas": reservations})
+}
+
+func getStockReservation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	reservationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	reservation, err := models.GetStockReservation(reservationId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Reserva não encontrada."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reservation)
+}
+
+func confirmStockReservation(ctx *gin.Context) {
+	changeStockReservation(ctx, func(tx *sql.Tx, reservation *models.StockReservation, userId int64) error {
+		return reservation.Confirm(tx, userId)
+	})
+}
+
+func releaseStockReservation(ctx *gin.Context) {
+	changeStockReservation(ctx, func(tx *sql.Tx, reservation *models.StockReservation, userId int64) error {
+		return reservation.Release(tx)
+	})
+}
+
+// changeStockReservation carrega a reserva com o controle de acesso e aplica a
+// operação informada em uma transação.
+func changeStockReservation(ctx *gin.Context, apply func(tx *sql.Tx, reservation *models.StockReservation, userId int64) error) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	reservationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	reservation, err := models.GetStockReservation(reservationId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Reserva não encontrada."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao processar a reserva. Falha interna."})
+		return
+	}
+
+	err = apply(tx, reservation, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		reservationErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao processar a reserva. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reservation)
+}
@@ -0,0 +1,328 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+func storageLocationErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para alterar locais deste estabelecimento."})
+	case errors.Is(err, models.ErrInvalidParentLocation):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Local pai inválido. Ele deve ser do mesmo estabelecimento e de um nível acima (AREA > CORREDOR > PRATELEIRA > POSICAO)."})
+	case errors.Is(err, models.ErrReservedLocationCode):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O código PADRAO é reservado ao local padrão do estabelecimento."})
+	case errors.Is(err, models.ErrLocationInUse):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "O local é o padrão do estabelecimento, possui sublocais ou tem produtos armazenados."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Local não encontrado."})
+	default:
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Já existe um local com esse código no estabelecimento."})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar o local."})
+	}
+}
+
+func createStorageLocation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	var location models.StorageLocation
+
+	err := ctx.ShouldBindJSON(&location)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe estabelecimento_id, tipo (AREA, CORREDOR, PRATELEIRA ou POSICAO) e codigo."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o local. Falha interna."})
+		return
+	}
+
+	err = location.Save(tx, role, userIdStr)
+
+	if err != nil {
+		tx.Rollback()
+		storageLocationErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o local. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, location)
+}
+
+func getStorageLocations(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.StorageLocationFilter{
+		ParentID: ctx.Query("parent_id"),
+		Tipo:     ctx.Query("tipo"),
+		Codigo:   ctx.Query("codigo"),
+	}
+
+	params, err := parseListParams(ctx, models.StorageLocationSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	locations, result, err := models.GetAllStorageLocations(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os locais."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, locations, params, result))
+}
+
+func getStorageLocation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	locationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	location, err := models.GetStorageLocation(locationId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Local não encontrado."})
+		return
+	}
+
+	products, err := models.GetLocationProducts(location.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os produtos do local."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"local": location, "produtos": products})
+}
+
+func updateStorageLocation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	locationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	location, err := models.GetStorageLocation(locationId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Local não encontrado."})
+		return
+	}
+
+	var updatedLocation models.StorageLocation
+
+	err = ctx.ShouldBindJSON(&updatedLocation)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Erro na requisição. Verifique os parametros obrigatórios e tente novamente."})
+		return
+	}
+
+	updatedLocation.ID = location.ID
+	updatedLocation.EstabelecimentoID = location.EstabelecimentoID
+	updatedLocation.Tipo = location.Tipo
+	updatedLocation.Padrao = location.Padrao
+	updatedLocation.CreatedAt = location.CreatedAt
+	updatedLocation.UpdatedAt = time.Now()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o local. Falha interna."})
+		return
+	}
+
+	err = updatedLocation.Update(tx)
+
+	if err != nil {
+		tx.Rollback()
+		storageLocationErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o local. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedLocation)
+}
+
+func deleteStorageLocation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	locationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	location, err := models.GetStorageLocation(locationId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Local não encontrado."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o local. Falha interna."})
+		return
+	}
+
+	err = location.Delete(tx)
+
+	if err != nil {
+		tx.Rollback()
+		storageLocationErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o local. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Local deletado com sucesso."})
+}
+
+func getProductLocationMoves(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	product, err := models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível encontrar nenhum produto com o id"})
+		return
+	}
+
+	moves, err := models.GetProductLocationMoves(product.ID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as trocas de local do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, moves)
+}
+
+// moveProductLocation guarda (sem origem, a partir do local padrão) ou troca
+// de local uma quantidade do produto.
+func moveProductLocation(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	var move models.LocationMove
+
+	err = ctx.ShouldBindJSON(&move)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe destino_location_id, quantidade e, para trocas de local, origem_location_id."})
+		return
+	}
+
+	move.ProductID = productId
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao movimentar o produto entre locais. Falha interna."})
+		return
+	}
+
+	err = move.Save(tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, models.ErrAccessDenied):
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para movimentar estoque deste estabelecimento."})
+		case errors.Is(err, models.ErrInvalidLocation):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Local inválido. Origem e destino devem ser locais do estabelecimento do produto."})
+		case errors.Is(err, models.ErrSameLocation):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Origem e destino devem ser locais diferentes."})
+		case errors.Is(err, models.ErrInsufficientLocationStock):
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Quantidade insuficiente no local de origem."})
+		case errors.Is(err, models.ErrFractionalQuantity):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de estoque do produto não aceita quantidades fracionadas."})
+		case errors.Is(err, models.ErrInvalidQuantity):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "A quantidade deve ser maior que zero."})
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Produto não encontrado."})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível movimentar o produto entre locais."})
+		}
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao movimentar o produto entre locais. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, move)
+}
@@ -0,0 +1,465 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func createSupplier(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	var supplier models.Supplier
+
+	err := ctx.ShouldBindJSON(&supplier)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Erro na estrutura da requisição. Verifique os parametros obrigatórios"})
+		return
+	}
+
+	formatedDoc, err := utils.FormatAndValidateCpfCnpj(supplier.CPFCNPJ)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "CPF ou CNPJ inválido."})
+		return
+	}
+
+	supplier.CPFCNPJ = formatedDoc
+
+	exists, err := utils.SupplierCpfCnpjExists(supplier.CPFCNPJ, supplier.EstabelecimentoID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar CPF/CNPJ"})
+		return
+	}
+
+	if exists {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Esse CPF ou CNPJ já foi cadastrado para outro fornecedor"})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o fornecedor. Falha interna."})
+		return
+	}
+
+	err = supplier.Save(tx, role, userIdStr)
+
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, models.ErrAccessDenied) {
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para cadastrar fornecedores neste estabelecimento."})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar o fornecedor."})
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditSupplier, supplier.ID, supplier.EstabelecimentoID, models.AuditCreate, nil, supplier)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o fornecedor. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao cadastrar o fornecedor. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, supplier)
+}
+
+func getSuppliers(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	filter := models.SupplierFilter{
+		RazaoSocial: ctx.Query("razao_social"),
+	}
+
+	if doc := ctx.Query("cpf_cnpj"); doc != "" {
+		formatedDoc, err := utils.FormatAndValidateCpfCnpj(doc)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "CPF ou CNPJ inválido."})
+			return
+		}
+		filter.CPFCNPJ = formatedDoc
+	}
+
+	params, err := parseListParams(ctx, models.SupplierSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	suppliers, result, err := models.GetAllSuppliers(role, userIdStr, filter, params)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os fornecedores."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listResponse(ctx, suppliers, params, result))
+}
+
+func getSupplier(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	supplierId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	supplier, err := models.GetSupplier(supplierId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Fornecedor não encontrado."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, supplier)
+}
+
+func updateSupplier(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	supplierId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	supplier, err := models.GetSupplier(supplierId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Fornecedor não encontrado."})
+		return
+	}
+
+	var updatedSupplier models.Supplier
+
+	err = ctx.ShouldBindJSON(&updatedSupplier)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Erro na requisição. Verifique os parametros obrigatórios e tente novamente."})
+		return
+	}
+
+	formatedDoc, err := utils.FormatAndValidateCpfCnpj(updatedSupplier.CPFCNPJ)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "CPF ou CNPJ inválido."})
+		return
+	}
+
+	updatedSupplier.ID = supplier.ID
+	updatedSupplier.CPFCNPJ = formatedDoc
+	updatedSupplier.EnderecoID = supplier.EnderecoID
+	updatedSupplier.EstabelecimentoID = supplier.EstabelecimentoID
+	updatedSupplier.CreatedAt = supplier.CreatedAt
+	updatedSupplier.UpdatedAt = time.Now()
+
+	exists, err := utils.SupplierCpfCnpjExistsExcludingSupplier(updatedSupplier.CPFCNPJ, updatedSupplier.ID, updatedSupplier.EstabelecimentoID)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar o CPF ou CNPJ."})
+		return
+	}
+
+	if exists {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "CPF ou CNPJ já cadastrado para outro fornecedor."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o fornecedor. Falha interna."})
+		return
+	}
+
+	err = updatedSupplier.Update(tx)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível atualizar o fornecedor."})
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditSupplier, updatedSupplier.ID, updatedSupplier.EstabelecimentoID, models.AuditUpdate, supplier, updatedSupplier)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o fornecedor. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o fornecedor. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedSupplier)
+}
+
+func deleteSupplier(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	supplierId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	supplier, err := models.GetSupplier(supplierId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Fornecedor não encontrado."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o fornecedor. Falha interna."})
+		return
+	}
+
+	err = supplier.Delete(tx)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível deletar o fornecedor."})
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditSupplier, supplier.ID, supplier.EstabelecimentoID, models.AuditDelete, supplier, nil)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o fornecedor. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o fornecedor. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Fornecedor deletado com sucesso."})
+}
+
+func getSupplierProducts(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	supplierId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	_, err = models.GetSupplier(supplierId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Fornecedor não encontrado."})
+		return
+	}
+
+	links, err := models.GetSupplierProducts(supplierId)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os produtos do fornecedor."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, links)
+}
+
+func getProductSuppliers(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	_, err = models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Produto não encontrado."})
+		return
+	}
+
+	links, err := models.GetProductSuppliers(productId)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar os fornecedores do produto."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, links)
+}
+
+// linkProductSupplier cria ou atualiza o vínculo do produto com o fornecedor.
+func linkProductSupplier(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	supplierId, err := strconv.ParseInt(ctx.Param("supplierId"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id do fornecedor"})
+		return
+	}
+
+	_, err = models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Produto não encontrado."})
+		return
+	}
+
+	var link models.ProductSupplier
+
+	err = ctx.ShouldBindJSON(&link)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Erro na requisição. Informe codigo_fornecedor e ultimo_custo, que não pode ser negativo."})
+		return
+	}
+
+	link.ProductID = productId
+	link.SupplierID = supplierId
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao vincular o fornecedor. Falha interna."})
+		return
+	}
+
+	err = link.Save(tx)
+
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, models.ErrSupplierEstablishmentMismatch):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "O fornecedor não pertence ao estabelecimento do produto."})
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Fornecedor não encontrado."})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível vincular o fornecedor."})
+		}
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao vincular o fornecedor. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, link)
+}
+
+func unlinkProductSupplier(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	productId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	supplierId, err := strconv.ParseInt(ctx.Param("supplierId"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id do fornecedor"})
+		return
+	}
+
+	_, err = models.GetProduct(productId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Produto não encontrado."})
+		return
+	}
+
+	link := models.ProductSupplier{ProductID: productId, SupplierID: supplierId}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao desvincular o fornecedor. Falha interna."})
+		return
+	}
+
+	err = link.Delete(tx)
+
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "O fornecedor não está vinculado a este produto."})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível desvincular o fornecedor."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao desvincular o fornecedor. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Fornecedor desvinculado com sucesso."})
+}
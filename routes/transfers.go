@@ -0,0 +1,167 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func transferErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Você não tem permissão para movimentar estoque deste estabelecimento."})
+	case errors.Is(err, models.ErrTransferDestination):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Estabelecimento de destino não encontrado."})
+	case errors.Is(err, models.ErrSameEstablishment):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Origem e destino devem ser estabelecimentos diferentes."})
+	case errors.Is(err, models.ErrInvalidTransferStatus):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "A transferência não está em um status que permita essa operação."})
+	case errors.Is(err, models.ErrInsufficientStock):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Estoque insuficiente no estabelecimento de origem."})
+	case errors.Is(err, models.ErrParentProductStock):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Produtos com variantes não podem ser transferidos. Transfira as variantes."})
+	case errors.Is(err, models.ErrFractionalQuantity):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A unidade de estoque do produto não aceita quantidades fracionadas."})
+	case errors.Is(err, sql.ErrNoRows):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "SKU não encontrado no estabelecimento de origem."})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível processar a transferência."})
+	}
+}
+
+func createTransfer(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	role := ctx.GetString("role")
+
+	var transfer models.Transfer
+
+	err := ctx.ShouldBindJSON(&transfer)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Requisição incompleta. Informe sku, quantidade, origem_estabelecimento_id e destino_estabelecimento_id."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao solicitar a transferência. Falha interna."})
+		return
+	}
+
+	err = transfer.Save(tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao solicitar a transferência. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, transfer)
+}
+
+func getTransfers(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	transfers, err := models.GetAllTransfers(role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível listar as transferências."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfers)
+}
+
+func getTransfer(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	transferId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	transfer, err := models.GetTransfer(transferId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Transferência não encontrada."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// changeTransferStatus executa uma transição de status da transferência dentro
+// de uma única transação.
+func changeTransferStatus(ctx *gin.Context, apply func(t *models.Transfer, tx *sql.Tx, role string, userId int64) error) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	transferId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id"})
+		return
+	}
+
+	transfer, err := models.GetTransfer(transferId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Transferência não encontrada."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a transferência. Falha interna."})
+		return
+	}
+
+	err = apply(transfer, tx, role, userIdRaw.(int64))
+
+	if err != nil {
+		tx.Rollback()
+		transferErrorResponse(ctx, err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar a transferência. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+func dispatchTransfer(ctx *gin.Context) {
+	changeTransferStatus(ctx, (*models.Transfer).Dispatch)
+}
+
+func receiveTransfer(ctx *gin.Context) {
+	changeTransferStatus(ctx, (*models.Transfer).Receive)
+}
+
+func cancelTransfer(ctx *gin.Context) {
+	changeTransferStatus(ctx, (*models.Transfer).Cancel)
+}
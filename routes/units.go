@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
+	"github.com/gin-gonic/gin"
+)
+
+func getUnits(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, models.UnitsOfMeasure)
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
 	"github.com/AntonioGuilhermeDev/InventoryHubApis/models"
 	"github.com/AntonioGuilhermeDev/InventoryHubApis/utils"
 	"github.com/gin-gonic/gin"
@@ -13,9 +14,9 @@ import (
 )
 
 func signup(ctx *gin.Context) {
-	var user models.User
+	var input models.SignupInput
 
-	err := ctx.ShouldBindJSON(&user)
+	err := ctx.ShouldBindJSON(&input)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"message": "Requisição incompleta. Todos os campos obrigatórios devem ser preenchidos.",
@@ -23,8 +24,72 @@ func signup(ctx *gin.Context) {
 		return
 	}
 
-	err = user.Save()
+	establishment := input.Estabelecimento
+
+	formatedDoc, err := utils.FormatAndValidateCpfCnpj(establishment.CPFCNPJ)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "CPF ou CNPJ inválido."})
+		return
+	}
+
+	establishment.CPFCNPJ = formatedDoc
+
+	exists, err := utils.CpfCnpjExists(establishment.CPFCNPJ)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar CPF/CNPJ"})
+		return
+	}
+
+	if exists {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Esse CPF ou CNPJ já foi cadastrado por outro estabelecimento"})
+		return
+	}
+
+	exists, err = utils.EmailExists(input.Email)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar Email"})
+		return
+	}
+
+	if exists {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Esse email já está sendo utilizado."})
+		return
+	}
+
+	tx, err := db.DB.Begin()
 	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Tente novamente mais tarde."})
+		return
+	}
+
+	err = establishment.Endereco.Save(tx)
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Erro ao salvar o endereço."})
+		return
+	}
+
+	establishment.EnderecoID = establishment.Endereco.ID
+
+	err = establishment.Save(tx)
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Erro ao salvar o estabelecimento."})
+		return
+	}
+
+	user := models.User{
+		Nome:              input.Nome,
+		Sobrenome:         input.Sobrenome,
+		Email:             input.Email,
+		Password:          input.Password,
+		Role:              "OWNER",
+		EstabelecimentoID: establishment.ID,
+	}
+
+	err = user.Save(tx)
+	if err != nil {
+		tx.Rollback()
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			ctx.JSON(http.StatusConflict, gin.H{
 				"message": "Esse email já está sendo utilizado.",
@@ -38,8 +103,28 @@ func signup(ctx *gin.Context) {
 		return
 	}
 
+	actor := models.Actor{UserID: user.ID, Role: user.Role}
+
+	err = models.RecordAudit(tx, actor, models.AuditEstablishment, establishment.ID, establishment.ID, models.AuditCreate, nil, establishment)
+	if err == nil {
+		err = models.RecordAudit(tx, actor, models.AuditUser, user.ID, establishment.ID, models.AuditCreate, nil, user.Public())
+	}
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível cadastrar. Tente novamente mais tarde."})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "Cadastro realizado com sucesso",
+		"message":         "Cadastro realizado com sucesso",
+		"estabelecimento": establishment,
 	})
 }
 
@@ -69,9 +154,16 @@ func login(ctx *gin.Context) {
 		return
 	}
 
+	refreshToken, err := models.IssueRefreshToken(user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao gerar token"})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "Login realizado com sucesso!",
-		"token":   "Bearer " + token,
+		"message":       "Login realizado com sucesso!",
+		"token":         "Bearer " + token,
+		"refresh_token": refreshToken,
 		"user": gin.H{
 			"id":                 user.ID,
 			"nome":               user.Nome,
@@ -86,14 +178,30 @@ func login(ctx *gin.Context) {
 }
 
 func getUsers(ctx *gin.Context) {
-	users, err := models.GetAllUsers()
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+
+	params, err := parseListParams(ctx, models.UserSortColumns)
+
+	if err != nil {
+		listParamsError(ctx, err)
+		return
+	}
+
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
+
+	users, result, err := models.GetAllUsers(role, userIdStr, params, includeDeleted)
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possivel listar os usuarios"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, users)
+	ctx.JSON(http.StatusOK, listResponse(ctx, users, params, result))
 
 }
 
@@ -108,7 +216,17 @@ func getUser(ctx *gin.Context) {
 		return
 	}
 
-	user, err := models.GetUserById(userId, role, userIdStr)
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
+
+	getUserById := models.GetUserById
+	if includeDeleted {
+		getUserById = models.GetUserByIdWithDeleted
+	}
+
+	user, err := getUserById(userId, role, userIdStr)
 
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Usuário não encontrado."})
@@ -146,7 +264,14 @@ func updateUser(ctx *gin.Context) {
 	}
 
 	updatedUser.ID = user.ID
+	updatedUser.CreatedAt = user.CreatedAt
 	updatedUser.UpdatedAt = time.Now()
+	updatedUser.EstabelecimentoID = user.EstabelecimentoID
+
+	if role != "OWNER" && updatedUser.Role == "OWNER" {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Apenas um OWNER pode atribuir o papel de OWNER."})
+		return
+	}
 
 	exists, err := utils.EmailExistsExcludingUser(updatedUser.Email, updatedUser.ID)
 
@@ -160,13 +285,35 @@ func updateUser(ctx *gin.Context) {
 		return
 	}
 
-	err = updatedUser.Update(role)
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o usuário. Falha interna."})
+		return
+	}
+
+	err = updatedUser.Update(tx, role)
 
 	if err != nil {
+		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possivel atualizar o usuário"})
 		return
 	}
 
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditUser, updatedUser.ID, updatedUser.EstabelecimentoID, models.AuditUpdate, user, updatedUser)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o usuário. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao atualizar o usuário. Falha interna."})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"message": "Usuário atualizado com sucesso",
 		"usuário": updatedUser,
@@ -195,12 +342,157 @@ func deleteUser(ctx *gin.Context) {
 
 	deletedUser.ID = user.ID
 
-	err = deletedUser.Delete()
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o usuário. Falha interna."})
+		return
+	}
+
+	err = deletedUser.Delete(tx)
 
 	if err != nil {
+		tx.Rollback()
 		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível deletar o usuário."})
 		return
 	}
 
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditUser, user.ID, user.EstabelecimentoID, models.AuditDelete, user, nil)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o usuário. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao deletar o usuário. Falha interna."})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Usúario deletado com sucesso"})
 }
+
+func restoreUser(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+	userId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id."})
+		return
+	}
+
+	user, err := models.GetUserByIdWithDeleted(userId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Usuário não encontrado."})
+		return
+	}
+
+	if user.DeletedAt == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O usuário não está excluído."})
+		return
+	}
+
+	if role != "OWNER" && user.Role == "OWNER" {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Apenas o OWNER pode restaurar outro OWNER."})
+		return
+	}
+
+	restoredUser := models.User{ID: user.ID}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o usuário. Falha interna."})
+		return
+	}
+
+	err = restoredUser.Restore(tx)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível restaurar o usuário."})
+		return
+	}
+
+	restoredPublicUser := *user
+	restoredPublicUser.DeletedAt = nil
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditUser, user.ID, user.EstabelecimentoID, models.AuditRestore, user, restoredPublicUser)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o usuário. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao restaurar o usuário. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, restoredPublicUser)
+}
+
+// purgeUser remove definitivamente um usuário que já foi excluído.
+func purgeUser(ctx *gin.Context) {
+	userIdRaw, _ := ctx.Get("userId")
+	userIdStr := fmt.Sprintf("%d", userIdRaw.(int64))
+	role := ctx.GetString("role")
+	userId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Não foi possível converter o id."})
+		return
+	}
+
+	user, err := models.GetUserByIdWithDeleted(userId, role, userIdStr)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Usuário não encontrado."})
+		return
+	}
+
+	if user.DeletedAt == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "O usuário precisa ser excluído antes de ser expurgado."})
+		return
+	}
+
+	purgedUser := models.User{ID: user.ID}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o usuário. Falha interna."})
+		return
+	}
+
+	err = purgedUser.Purge(tx)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Não foi possível expurgar o usuário."})
+		return
+	}
+
+	err = models.RecordAudit(tx, actorFromContext(ctx), models.AuditUser, user.ID, user.EstabelecimentoID, models.AuditPurge, user, nil)
+
+	if err != nil {
+		tx.Rollback()
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o usuário. Falha ao registrar a auditoria."})
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": "Erro ao expurgar o usuário. Falha interna."})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Usuário expurgado com sucesso"})
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"os"
 	"time"
@@ -8,6 +11,11 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	AccessTokenTTL  = time.Minute * 15
+	RefreshTokenTTL = time.Hour * 24 * 7
+)
+
 func GetSecretKey() (string, error) {
 	secret := os.Getenv("SECRET_KEY")
 	if secret == "" {
@@ -24,11 +32,37 @@ func GenerateToken(email, role string, userId int64) (string, error) {
 		return "", errors.New("SECRET_KEY não encontrada no .env")
 	}
 
+	jti, err := GenerateRandomToken()
+
+	if err != nil {
+		return "", err
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"email":  email,
 		"role":   role,
 		"userId": userId,
-		"exp":    time.Now().Add(time.Hour * 2).Unix(),
+		"jti":    jti,
+		"exp":    time.Now().Add(AccessTokenTTL).Unix(),
 	})
 	return token.SignedString([]byte(secretKey))
 }
+
+// GenerateRandomToken gera um valor aleatório de 256 bits em hexadecimal,
+// usado como jti e como refresh token.
+func GenerateRandomToken() (string, error) {
+	bytes := make([]byte, 32)
+
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
+// HashToken retorna o SHA-256 do token. Apenas o hash é persistido no banco.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
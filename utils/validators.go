@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"regexp"
+	"strings"
 
 	"github.com/AntonioGuilhermeDev/InventoryHubApis/db"
 	"github.com/klassmann/cpfcnpj"
@@ -55,16 +56,81 @@ func FormatAndValidateCpfCnpj(doc string) (string, error) {
 	}
 }
 
-func SKUExists(sku string) (bool, error) {
+func SKUExists(sku string, estabelecimentoId int64) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1)`
-	err := db.DB.QueryRow(query, sku).Scan(&exists)
+	query := `SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1 AND estabelecimento_id = $2)`
+	err := db.DB.QueryRow(query, sku, estabelecimentoId).Scan(&exists)
 	return exists, err
 }
 
-func SKUExistsForOtherProduct(sku string, id int64) (bool, error) {
+func SKUExistsForOtherProduct(sku string, id, estabelecimentoId int64) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1 AND id != $2)`
-	err := db.DB.QueryRow(query, sku, id).Scan(&exists)
+	query := `SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1 AND estabelecimento_id = $2 AND id != $3)`
+	err := db.DB.QueryRow(query, sku, estabelecimentoId, id).Scan(&exists)
 	return exists, err
 }
+
+func SupplierCpfCnpjExists(cpf_cnpj string, estabelecimentoId int64) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM suppliers WHERE cpf_cnpj = $1 AND estabelecimento_id = $2)`
+	err := db.DB.QueryRow(query, cpf_cnpj, estabelecimentoId).Scan(&exists)
+	return exists, err
+}
+
+func SupplierCpfCnpjExistsExcludingSupplier(cpf_cnpj string, id, estabelecimentoId int64) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM suppliers WHERE cpf_cnpj = $1 AND estabelecimento_id = $2 AND id != $3)`
+	err := db.DB.QueryRow(query, cpf_cnpj, estabelecimentoId, id).Scan(&exists)
+	return exists, err
+}
+
+// ValidateGTIN confere o tamanho e o dígito verificador GS1 de um código
+// GTIN-8, GTIN-12, GTIN-13 ou GTIN-14 e o devolve normalizado em GTIN-14.
+func ValidateGTIN(code string) (string, error) {
+	formated := regexp.MustCompile(`\s`).ReplaceAllString(code, "")
+
+	if !regexp.MustCompile(`^\d+$`).MatchString(formated) {
+		return "", errors.New("código de barras inválido")
+	}
+
+	switch len(formated) {
+	case 8, 12, 13, 14:
+	default:
+		return "", errors.New("código de barras inválido")
+	}
+
+	if GTINCheckDigit(formated[:len(formated)-1]) != formated[len(formated)-1] {
+		return "", errors.New("dígito verificador inválido")
+	}
+
+	return NormalizeGTIN(formated), nil
+}
+
+// NormalizeGTIN remove espaços e completa o código com zeros à esquerda até
+// 14 dígitos, para que o mesmo item lido como GTIN-8, GTIN-12 ou GTIN-13 seja
+// comparado como o mesmo GTIN-14. O dígito verificador não muda.
+func NormalizeGTIN(code string) string {
+	formated := regexp.MustCompile(`\s`).ReplaceAllString(code, "")
+
+	if len(formated) >= 14 {
+		return formated
+	}
+
+	return strings.Repeat("0", 14-len(formated)) + formated
+}
+
+// GTINCheckDigit calcula o dígito verificador GS1 para os dígitos informados:
+// da direita para a esquerda, os pesos alternam entre 3 e 1.
+func GTINCheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			sum += digit * 3
+		} else {
+			sum += digit
+		}
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,56 @@
+package utils
+
+import "testing"
+
+func TestGTINCheckDigit(t *testing.T) {
+	tests := []struct {
+		name   string
+		digits string
+		want   byte
+	}{
+		{"GTIN-8", "9638507", '4'},
+		{"GTIN-12", "01234567890", '5'},
+		{"GTIN-13", "400638133393", '1'},
+		{"GTIN-14", "0001234560001", '2'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GTINCheckDigit(tt.digits); got != tt.want {
+				t.Errorf("GTINCheckDigit(%q) = %q, want %q", tt.digits, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGTIN(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{"GTIN-8", "96385074", "00000096385074", false},
+		{"GTIN-12", "012345678905", "00012345678905", false},
+		{"GTIN-13", "4006381333931", "04006381333931", false},
+		{"GTIN-14", "00012345600012", "00012345600012", false},
+		{"GTIN-13 do mesmo item do GTIN-12", "0012345678905", "00012345678905", false},
+		{"com espaços", "4006381 333931", "04006381333931", false},
+		{"dígito verificador errado", "4006381333932", "", true},
+		{"tamanho inválido", "1234567890", "", true},
+		{"não numérico", "40063813339A1", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateGTIN(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateGTIN(%q) error = %v, wantErr %v", tt.code, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ValidateGTIN(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}